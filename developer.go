@@ -6,14 +6,22 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"cloud.google.com/go/firestore"
 	"github.com/fatih/color"
 
 	firebase "firebase.google.com/go"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+
+	"game/ranking"
 )
 
 type Team struct {
@@ -21,11 +29,81 @@ type Team struct {
 	Score    int    `json:"score"`
 	Attempts int    `json:"attempts"`
 	Password string `json:"password"` // Add this field
+
+	SeenRiddles []string `json:"seenRiddles"` // Keys of every riddle the team has been served
 }
 
 type Riddle struct {
-	Question string `json:"question"`
-	Answer   string `json:"answer"`
+	Question   string `json:"question"`
+	Answer     string `json:"answer"`
+	Strictness string `json:"strictness" firestore:"strictness"` // exact, normal or lenient; empty means normal
+
+	Aliases  []string `json:"aliases" firestore:"aliases"`   // Other accepted answers
+	Patterns []string `json:"patterns" firestore:"patterns"` // Regular expressions a whole guess may match
+	Hints    []string `json:"hints" firestore:"hints"`       // Revealed in order by the hint command
+
+	Difficulty string `json:"difficulty" firestore:"difficulty"` // easy, medium or hard; empty means medium
+	Category   string `json:"category" firestore:"category"`     // e.g. tech, wordplay, general knowledge
+}
+
+var strictnessLevels = []string{"exact", "normal", "lenient"}
+
+var difficulties = []string{"easy", "medium", "hard"}
+
+// Session is a single play-through of the game by a team, as saved by the game.
+type Session struct {
+	Team         string    `json:"team" firestore:"team"`
+	Attempt      int       `json:"attempt" firestore:"attempt"`
+	Status       string    `json:"status" firestore:"status"`
+	StartedAt    time.Time `json:"startedAt" firestore:"startedAt"`
+	UpdatedAt    time.Time `json:"updatedAt" firestore:"updatedAt"`
+	EndedAt      time.Time `json:"endedAt" firestore:"endedAt"`
+	EndReason    string    `json:"endReason" firestore:"endReason"`
+	Score        int       `json:"score" firestore:"score"`
+	WrongGuesses int       `json:"wrongGuesses" firestore:"wrongGuesses"`
+	Answered     int       `json:"answered" firestore:"answered"`
+	Total        int       `json:"total" firestore:"total"`
+	LivesLeft    int       `json:"livesLeft" firestore:"livesLeft"`
+	Rank         int       `json:"rank" firestore:"rank"`
+
+	// Seed picked the riddles; OrderSeed, when set, shuffled their order
+	// separately. Together they reproduce exactly what the team was served.
+	Seed      int64 `json:"seed" firestore:"seed"`
+	OrderSeed int64 `json:"orderSeed" firestore:"orderSeed"`
+
+	Results []QuestionResult `json:"results" firestore:"results"`
+	Rounds  []RoundResult    `json:"rounds" firestore:"rounds"`
+
+	Progress *Progress `json:"progress,omitempty" firestore:"progress,omitempty"` // Checkpoint of a game still in play
+}
+
+// Progress is the part of a game's checkpoint the admin tool reads.
+type Progress struct {
+	Remaining float64 `json:"remaining" firestore:"remaining"` // Seconds left on the round's clock
+}
+
+// RoundResult is how a team did in one themed round of a session.
+type RoundResult struct {
+	Name      string `json:"name" firestore:"name"`
+	EndReason string `json:"endReason" firestore:"endReason"`
+	Score     int    `json:"score" firestore:"score"`
+	Answered  int    `json:"answered" firestore:"answered"`
+	Total     int    `json:"total" firestore:"total"`
+}
+
+// QuestionResult is how a team did on one riddle of a session.
+type QuestionResult struct {
+	Question string  `json:"question" firestore:"question"`
+	Answer   string  `json:"answer" firestore:"answer"`
+	Guess    string  `json:"guess" firestore:"guess"`
+	Matched  string  `json:"matched" firestore:"matched"` // The accepted answer or pattern the guess matched
+	Correct  bool    `json:"correct" firestore:"correct"`
+	Seconds  float64 `json:"seconds" firestore:"seconds"`
+	Points   int     `json:"points" firestore:"points"`
+
+	HintsUsed int  `json:"hintsUsed" firestore:"hintsUsed"`
+	Passed    bool `json:"passed" firestore:"passed"`   // Put off until the end at least once
+	Skipped   bool `json:"skipped" firestore:"skipped"` // Given up without a guess
 }
 
 var firebaseApp *firebase.App
@@ -41,7 +119,34 @@ func initFirebase() {
 	firebaseApp = app
 }
 
+// writeMu lets shutdown wait for a write in flight before exiting.
+var writeMu sync.RWMutex
+
+// beginWrite holds off shutdown until the write it guards is done. Call it
+// as defer beginWrite()().
+func beginWrite() func() {
+	writeMu.RLock()
+	return writeMu.RUnlock
+}
+
+// handleShutdown exits cleanly on SIGINT or SIGTERM, once any write in
+// flight has finished.
+func handleShutdown() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		blue := color.New(color.FgBlue).SprintFunc()
+
+		writeMu.Lock()
+		fmt.Println(blue("\nExiting..."))
+		os.Exit(0)
+	}()
+}
+
 func changePasswordInFirebase(currentPassword, newPassword string) error {
+	defer beginWrite()()
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
@@ -78,6 +183,7 @@ func changePasswordInFirebase(currentPassword, newPassword string) error {
 }
 
 func saveTeamToFirebase(team Team) {
+	defer beginWrite()()
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
@@ -118,11 +224,12 @@ func viewTeamsInFirebase() {
 		}
 
 		// Display the team details, including the password
-		fmt.Printf(green("Team:")+" %s"+green(",\tScore:")+" %d"+green(",\tAttempts:")+" %d"+green(",")+red("\tPassword:")+" %s\n", team.Name, team.Score, team.Attempts, team.Password)
+		fmt.Printf(green("Team:")+" %s"+green(",\tScore:")+" %d"+green(",\tAttempts:")+" %d"+green(",\tRiddles seen:")+" %d"+green(",")+red("\tPassword:")+" %s\n", team.Name, team.Score, team.Attempts, len(team.SeenRiddles), team.Password)
 	}
 }
 
 func addRiddleToFirebase(riddle Riddle) {
+	defer beginWrite()()
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
@@ -138,6 +245,7 @@ func addRiddleToFirebase(riddle Riddle) {
 
 func addApprovedTeamToFirebase(teamName string) {
 	blue := color.New(color.FgBlue).SprintFunc()
+	defer beginWrite()()
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
@@ -156,6 +264,7 @@ func addApprovedTeamToFirebase(teamName string) {
 }
 
 func setGameDurationInFirebase(duration int) error {
+	defer beginWrite()()
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
@@ -173,6 +282,287 @@ func setGameDurationInFirebase(duration int) error {
 	return nil
 }
 
+func setIgnoreAccentsInFirebase(ignoreAccents bool) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("matching").Set(ctx, map[string]interface{}{
+		"ignoreAccents": ignoreAccents,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting accent matching in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func setHintCostInFirebase(cost int) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("hints").Set(ctx, map[string]interface{}{
+		"cost": cost,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting hint cost in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func setSkipPenaltyInFirebase(penalty int) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("skip").Set(ctx, map[string]interface{}{
+		"penalty": penalty,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting skip penalty in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func setDifficultyMixInFirebase(mix map[string]int, rampUp bool) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	data := map[string]interface{}{"rampUp": rampUp}
+	for difficulty, count := range mix {
+		data[difficulty] = count
+	}
+	_, err = client.Collection("game_settings").Doc("selection").Set(ctx, data)
+	if err != nil {
+		return fmt.Errorf("error setting difficulty mix in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+// setRoundsInFirebase saves the themed rounds a game is played in. An empty
+// list goes back to a single game of random riddles.
+func setRoundsInFirebase(rounds []map[string]interface{}) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("rounds").Set(ctx, map[string]interface{}{
+		"rounds": rounds,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting rounds in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+// setCompetitionInFirebase fixes the riddles for an event. A zero seed and
+// no riddle IDs turns competition mode off.
+func setCompetitionInFirebase(seed int64, riddleIDs []string, sameOrder bool) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("competition").Set(ctx, map[string]interface{}{
+		"seed":      seed,
+		"riddles":   riddleIDs,
+		"sameOrder": sameOrder,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting competition mode in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func setRepeatPolicyInFirebase(policy string) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("history").Set(ctx, map[string]interface{}{
+		"repeatPolicy": policy,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting repeat policy in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func clearSeenRiddlesInFirebase(teamName string) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("teams").Doc(teamName).Update(ctx, []firestore.Update{
+		{Path: "seenRiddles", Value: []string{}},
+	})
+	if err != nil {
+		return fmt.Errorf("error clearing riddle history in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func setRevealPolicyInFirebase(policy string) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("reveal").Set(ctx, map[string]interface{}{
+		"policy": policy,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting reveal policy in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func setAttemptPolicyInFirebase(maxAttempts int, scoring string) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("attempts").Set(ctx, map[string]interface{}{
+		"maxAttempts": maxAttempts,
+		"scoring":     scoring,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting attempt policy in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func setResumeRulesInFirebase(enabled bool, graceMinutes int) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("resume").Set(ctx, map[string]interface{}{
+		"enabled":      enabled,
+		"graceMinutes": graceMinutes,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting resume rules in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+func setTiebreakersInFirebase(tiebreakers []string) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("ranking").Set(ctx, map[string]interface{}{
+		"tiebreakers": tiebreakers,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting tiebreakers in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+// getRaceModeFromFirebase reports whether teams on the game server race
+// each other.
+func getRaceModeFromFirebase() (bool, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	doc, err := client.Collection("game_settings").Doc("race").Get(ctx)
+	if doc != nil && !doc.Exists() {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error retrieving race mode: %v", err)
+	}
+	enabled, _ := doc.Data()["enabled"].(bool)
+	return enabled, nil
+}
+
+func setRaceModeInFirebase(enabled bool, teams, riddles, seconds int) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("game_settings").Doc("race").Set(ctx, map[string]interface{}{
+		"enabled": enabled,
+		"teams":   teams,
+		"riddles": riddles,
+		"seconds": seconds,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting race mode in Firebase: %v", err)
+	}
+
+	return nil
+}
+
 func displayLogo() {
 	yellow := color.New(color.FgYellow).SprintFunc()
 	fmt.Println(`
@@ -188,6 +578,7 @@ func displayLogo() {
 }
 
 func deleteAllRiddlesFromFirebase() error {
+	defer beginWrite()()
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
@@ -268,50 +659,1042 @@ func viewRiddlesInFirebase() {
 		}
 		var riddle Riddle
 		doc.DataTo(&riddle)
-		fmt.Printf(green("Question: ")+"%s\n"+green("Answer: ")+"%s\n\n", riddle.Question, riddle.Answer)
+		fmt.Printf(green("Question: ")+"%s\n"+green("Answer: ")+"%s\n"+green("Difficulty: ")+"%s\n"+green("Strictness: ")+"%s\n", riddle.Question, riddle.Answer, difficultyOrDefault(riddle.Difficulty), strictnessOrDefault(riddle.Strictness))
+		if riddle.Category != "" {
+			fmt.Printf(green("Category: ")+"%s\n", riddle.Category)
+		}
+		if len(riddle.Aliases) > 0 {
+			fmt.Printf(green("Also accepted: ")+"%s\n", strings.Join(riddle.Aliases, ", "))
+		}
+		if len(riddle.Patterns) > 0 {
+			fmt.Printf(green("Patterns: ")+"%s\n", strings.Join(riddle.Patterns, "  "))
+		}
+		for i, hint := range riddle.Hints {
+			fmt.Printf(green("Hint %d: ")+"%s\n", i+1, hint)
+		}
+		fmt.Println()
 	}
 }
 
-func developerInterface() {
-	reader := bufio.NewReader(os.Stdin)
-	blue := color.New(color.FgBlue).SprintFunc()
+// readDifficulty asks for a difficulty level, returning "" for the default.
+func readDifficulty(reader *bufio.Reader) (string, bool) {
 	green := color.New(color.FgGreen).SprintFunc()
 	red := color.New(color.FgHiRed).SprintFunc()
 
-	displayLogo()
+	fmt.Print(green("Enter the difficulty (easy/medium/hard, blank for medium): "))
+	difficulty, _ := reader.ReadString('\n')
+	difficulty = strings.TrimSpace(strings.ToLower(difficulty))
+	if difficulty == "" {
+		return "", true
+	}
+	for _, level := range difficulties {
+		if difficulty == level {
+			return difficulty, true
+		}
+	}
+	fmt.Println(red("Invalid difficulty. Use easy, medium or hard."))
+	return "", false
+}
 
-	for {
-		fmt.Println(blue("  Developer CLI\n"))
-		fmt.Println("1. View Teams")
-		fmt.Println("2. Add Riddle")
-		fmt.Println("3. Change admin Password")
-		fmt.Println("4. Add Approved Team")
-		fmt.Println("5. View Approved Teams")
-		fmt.Println("6. Set Game Duration")
-		fmt.Println("7. Delete All Riddles")
-		fmt.Println("8. View All Riddles") // New option
-		fmt.Println("9. Exit")
-		fmt.Print(green("Choose an option: "))
+// readAliases asks for extra accepted answers as a comma-separated list.
+func readAliases(reader *bufio.Reader) []string {
+	green := color.New(color.FgGreen).SprintFunc()
 
-		var choice int
-		fmt.Scanln(&choice)
+	fmt.Print(green("Enter other accepted answers, separated by commas (optional): "))
+	line, _ := reader.ReadString('\n')
 
-		switch choice {
-		case 1:
-			viewTeamsInFirebase()
-			fmt.Println()
-		case 2:
-			fmt.Print(green("Enter the riddle question: "))
-			question, _ := reader.ReadString('\n')
-			question = strings.TrimSpace(question)
+	var aliases []string
+	for _, alias := range strings.Split(line, ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
 
-			fmt.Print(green("Enter the riddle answer: "))
+// readHints asks for hints one per line, in the order they should be
+// revealed, until a blank line.
+func readHints(reader *bufio.Reader) []string {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Println(green("Enter hints in the order they should be revealed, one per line. Leave blank to finish:"))
+	var hints []string
+	for {
+		fmt.Print("> ")
+		hint, _ := reader.ReadString('\n')
+		hint = strings.TrimSpace(hint)
+		if hint == "" {
+			return hints
+		}
+		hints = append(hints, hint)
+	}
+}
+
+// readPatterns asks for answer patterns one per line until a blank line,
+// rejecting any that aren't valid regular expressions.
+func readPatterns(reader *bufio.Reader) []string {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	fmt.Println(green("Enter answer patterns (regular expressions), one per line. Leave blank to finish:"))
+	var patterns []string
+	for {
+		fmt.Print("> ")
+		pattern, _ := reader.ReadString('\n')
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return patterns
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			fmt.Println(red(fmt.Sprintf("Invalid pattern: %v", err)))
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+}
+
+func strictnessOrDefault(strictness string) string {
+	if strictness == "" {
+		return "normal"
+	}
+	return strictness
+}
+
+func difficultyOrDefault(difficulty string) string {
+	if difficulty == "" {
+		return "medium"
+	}
+	return difficulty
+}
+
+func getRiddlesFromFirebase() ([]string, []Riddle, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("riddles").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error retrieving riddles: %v", err)
+	}
+
+	var ids []string
+	var riddles []Riddle
+	for _, doc := range docs {
+		var riddle Riddle
+		if err := doc.DataTo(&riddle); err != nil {
+			return nil, nil, fmt.Errorf("error converting document data to riddle: %v", err)
+		}
+		ids = append(ids, doc.Ref.ID)
+		riddles = append(riddles, riddle)
+	}
+
+	return ids, riddles, nil
+}
+
+func updateRiddleInFirebase(id string, riddle Riddle) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Collection("riddles").Doc(id).Set(ctx, riddle)
+	if err != nil {
+		return fmt.Errorf("error updating riddle in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+// chooseRiddle lists the riddles and asks for one by number, returning its
+// document ID and contents.
+func chooseRiddle(reader *bufio.Reader) (string, Riddle, bool) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	ids, riddles, err := getRiddlesFromFirebase()
+	if err != nil {
+		fmt.Println(red(fmt.Sprintf("Error fetching riddles: %v", err)))
+		return "", Riddle{}, false
+	}
+	if len(riddles) == 0 {
+		fmt.Println(red("There are no riddles yet."))
+		return "", Riddle{}, false
+	}
+
+	for i, riddle := range riddles {
+		fmt.Printf("%d. %s "+green("(%s)")+"\n", i+1, riddle.Question, riddle.Answer)
+	}
+	fmt.Print(green("Choose a riddle: "))
+	numberStr, _ := reader.ReadString('\n')
+	number, err := strconv.Atoi(strings.TrimSpace(numberStr))
+	if err != nil || number < 1 || number > len(riddles) {
+		fmt.Println(red("Invalid riddle number."))
+		return "", Riddle{}, false
+	}
+
+	return ids[number-1], riddles[number-1], true
+}
+
+// readStrictness asks for a strictness level, returning "" for the default.
+func readStrictness(reader *bufio.Reader) (string, bool) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	fmt.Print(green("Enter the answer strictness (exact/normal/lenient, blank for normal): "))
+	strictness, _ := reader.ReadString('\n')
+	strictness = strings.TrimSpace(strings.ToLower(strictness))
+	if strictness == "" {
+		return "", true
+	}
+	for _, level := range strictnessLevels {
+		if strictness == level {
+			return strictness, true
+		}
+	}
+	fmt.Println(red("Invalid strictness. Use exact, normal or lenient."))
+	return "", false
+}
+
+func viewSessionsInFirebase(teamName string) {
+	blue := color.New(color.FgBlue).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		log.Fatalf("Error creating Firestore client: %v\n", err)
+	}
+	defer client.Close()
+
+	query := client.Collection("sessions").Query
+	if teamName != "" {
+		query = query.Where("team", "==", teamName)
+	}
+	iter := query.Documents(ctx)
+	fmt.Println(blue("\nSessions:"))
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error iterating through sessions: %v\n", err)
+		}
+		var session Session
+		if err := doc.DataTo(&session); err != nil {
+			log.Fatalf("Error converting document data to Session struct: %v\n", err)
+		}
+
+		status := session.EndReason
+		switch session.Status {
+		case "playing":
+			status = "in progress, last saved " + session.UpdatedAt.Local().Format("15:04:05")
+		case "aborted":
+			status = session.EndReason + ", last saved " + session.UpdatedAt.Local().Format("15:04:05")
+		}
+		fmt.Printf(green("Team:")+" %s"+green(",\tAttempt:")+" %d"+green(",\tResult:")+" %s"+green(",\tScore:")+" %d"+green(",\tLives:")+" %d"+green(",\tRank:")+" %d\n",
+			session.Team, session.Attempt, status, session.Score, session.LivesLeft, session.Rank)
+		fmt.Printf("  %s %d, %s %d\n", green("Seed:"), session.Seed, green("Order seed:"), session.OrderSeed)
+		for _, round := range session.Rounds {
+			fmt.Printf("  %s %s: %s, %d/%d answered, %d points\n", green("Round"), round.Name, round.EndReason, round.Answered, round.Total, round.Score)
+		}
+		for i, result := range session.Results {
+			outcome := red("wrong")
+			if result.Correct {
+				outcome = blue("correct")
+				if result.Matched != "" && result.Matched != result.Answer {
+					outcome = blue(fmt.Sprintf("correct via %q", result.Matched))
+				}
+			} else if result.Skipped {
+				outcome = red("skipped")
+			}
+			if result.Passed {
+				outcome += ", passed earlier"
+			}
+			fmt.Printf("  %d. %s\n     guess: %q, answer: %q, %s, %.0fs, %d hints, %d points\n", i+1, result.Question, result.Guess, result.Answer, outcome, result.Seconds, result.HintsUsed, result.Points)
+		}
+		fmt.Println()
+	}
+}
+
+// standing is a team's place on the leaderboard, ranked by the same code
+// as the game's, with what the admin sees of the team besides.
+type standing struct {
+	ranking.Standing
+	Attempts int      // How many attempts the team has started
+	Latest   *Session // The team's most recent attempt, if it has started one
+}
+
+func getTeamsFromFirebase() ([]Team, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("teams").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving teams: %v", err)
+	}
+
+	var teams []Team
+	for _, doc := range docs {
+		var team Team
+		if err := doc.DataTo(&team); err != nil {
+			return nil, fmt.Errorf("error parsing team data: %v", err)
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+func getSessionsFromFirebase() ([]Session, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("sessions").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving sessions: %v", err)
+	}
+
+	var sessions []Session
+	for _, doc := range docs {
+		var session Session
+		if err := doc.DataTo(&session); err != nil {
+			return nil, fmt.Errorf("error parsing session data: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// getRankingRulesFromFirebase returns how attempt scores combine and the
+// tiebreakers for teams on the same score, with the game's defaults.
+func getRankingRulesFromFirebase() (string, []string, error) {
+	scoring := ranking.ScoreLatest
+	tiebreakers := ranking.DefaultTiebreakers
+
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return scoring, tiebreakers, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	if doc, err := client.Collection("game_settings").Doc("attempts").Get(ctx); err == nil {
+		if value, ok := doc.Data()["scoring"].(string); ok && value != "" {
+			scoring = value
+		}
+	}
+	if doc, err := client.Collection("game_settings").Doc("ranking").Get(ctx); err == nil {
+		if values, ok := doc.Data()["tiebreakers"].([]interface{}); ok {
+			tiebreakers = nil
+			for _, value := range values {
+				if value, ok := value.(string); ok {
+					tiebreakers = append(tiebreakers, value)
+				}
+			}
+		}
+	}
+
+	return scoring, tiebreakers, nil
+}
+
+// rankTeams puts the teams in leaderboard order, the same way the game
+// does.
+func rankTeams(teams []Team, sessions []Session, scoring string, tiebreakers []string) []standing {
+	attempts := make(map[string][]ranking.Attempt)
+	latest := make(map[string]*Session)
+	for i, session := range sessions {
+		attempt := ranking.Attempt{
+			Number:       session.Attempt,
+			Score:        session.Score,
+			WrongGuesses: session.WrongGuesses,
+			Finished:     session.Status == "finished",
+			EndedAt:      session.EndedAt,
+		}
+		for _, question := range session.Results {
+			attempt.Seconds += question.Seconds
+			attempt.Hints += question.HintsUsed
+		}
+		attempts[session.Team] = append(attempts[session.Team], attempt)
+		if latest[session.Team] == nil || session.Attempt > latest[session.Team].Attempt {
+			latest[session.Team] = &sessions[i]
+		}
+	}
+
+	ranked := make([]ranking.Standing, len(teams))
+	started := make(map[string]int)
+	for i, team := range teams {
+		ranked[i] = ranking.TeamStanding(team.Name, team.Score, attempts[team.Name], scoring)
+		started[team.Name] = team.Attempts
+	}
+	ranking.Rank(ranked, tiebreakers)
+
+	standings := make([]standing, len(ranked))
+	for i, ranked := range ranked {
+		standings[i] = standing{Standing: ranked, Attempts: started[ranked.Team], Latest: latest[ranked.Team]}
+	}
+	return standings
+}
+
+// playStatus describes where a team's latest attempt is at, and how long it
+// has left if it is still in play.
+func playStatus(latest *Session) (string, string) {
+	if latest == nil {
+		return "not started", "-"
+	}
+	if latest.Progress == nil {
+		latest.Progress = &Progress{}
+	}
+	switch latest.Status {
+	case "playing":
+		// The clock has kept running since the last checkpoint
+		remaining := time.Duration(latest.Progress.Remaining*float64(time.Second)) - time.Since(latest.UpdatedAt)
+		return "playing", formatDuration(max(remaining, 0))
+	case "aborted":
+		return "interrupted", formatDuration(time.Duration(latest.Progress.Remaining * float64(time.Second)))
+	default:
+		return "finished", "-"
+	}
+}
+
+// formatDuration renders d the way the game shows times, e.g. "4min 30sec".
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	seconds := int(d.Seconds()) % 60
+	return fmt.Sprintf("%dmin %dsec", minutes, seconds)
+}
+
+// drawLeaderboard clears the screen and draws the ranked teams on it.
+func drawLeaderboard() {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	blue := color.New(color.FgBlue).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	fmt.Print("\033[H\033[2J")
+	fmt.Println(yellow("\n\t\tHangman Leaderboard\n"))
+
+	scoring, tiebreakers, err := getRankingRulesFromFirebase()
+	if err != nil {
+		fmt.Println(red(fmt.Sprintf("Error getting ranking rules: %v. Using defaults.", err)))
+	}
+	teams, err := getTeamsFromFirebase()
+	if err != nil {
+		fmt.Println(red(fmt.Sprintf("Error fetching teams: %v", err)))
+		return
+	}
+	sessions, err := getSessionsFromFirebase()
+	if err != nil {
+		fmt.Println(red(fmt.Sprintf("Error fetching sessions: %v", err)))
+		return
+	}
+
+	fmt.Println(blue(fmt.Sprintf("%-5s %-25s %6s %8s  %-12s %s", "Rank", "Team", "Score", "Attempts", "Status", "Time left")))
+	for _, standing := range rankTeams(teams, sessions, scoring, tiebreakers) {
+		status, timeLeft := playStatus(standing.Latest)
+		statusText := fmt.Sprintf("%-12s", status)
+		if status == "playing" {
+			statusText = green(statusText)
+		} else if status == "interrupted" {
+			statusText = red(statusText)
+		}
+		fmt.Printf("%-5d %-25s %6d %8d  %s %s\n", standing.Rank, standing.Team, standing.Score, standing.Attempts, statusText, timeLeft)
+	}
+	fmt.Printf("\nUpdated %s. Press Enter to go back.\n", time.Now().Format("15:04:05"))
+}
+
+// showLeaderboard shows the ranked teams, refreshing every few seconds so
+// it can be left up on a hall screen, until Enter is pressed.
+func showLeaderboard(reader *bufio.Reader) {
+	done := untilEnter(reader)
+
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+	for {
+		drawLeaderboard()
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// The stages of an event, as the game reads them.
+const (
+	eventOpen      = "open"
+	eventCountdown = "countdown"
+	eventRunning   = "running"
+	eventPaused    = "paused"
+	eventClosed    = "closed"
+)
+
+// Event is the admin's control over when games start.
+type Event struct {
+	State    string    `firestore:"state"`
+	StartsAt time.Time `firestore:"startsAt"` // End of the countdown
+}
+
+func getEventFromFirebase() (Event, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return Event{}, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	var event Event
+	doc, err := client.Collection("game_settings").Doc("event").Get(ctx)
+	if doc != nil && !doc.Exists() {
+		return event, nil
+	}
+	if err != nil {
+		return event, fmt.Errorf("error retrieving event: %v", err)
+	}
+	if err := doc.DataTo(&event); err != nil {
+		return event, fmt.Errorf("error converting document data to event: %v", err)
+	}
+	return event, nil
+}
+
+// setEventInFirebase moves the event to state. An empty state takes the
+// event away, leaving teams to start whenever they like.
+func setEventInFirebase(state string, startsAt time.Time) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	doc := client.Collection("game_settings").Doc("event")
+	if state == "" {
+		_, err = doc.Delete(ctx)
+	} else {
+		_, err = doc.Set(ctx, map[string]interface{}{
+			"state":    state,
+			"startsAt": startsAt,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("error setting event in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+// eventText describes the event for the admin.
+func eventText(event Event) string {
+	switch {
+	case event.State == "":
+		return "none, teams start whenever they like"
+	case event.State == eventCountdown && time.Now().Before(event.StartsAt):
+		return fmt.Sprintf("counting down, starts at %s", event.StartsAt.Local().Format("15:04:05"))
+	case event.State == eventCountdown:
+		return eventRunning
+	}
+	return event.State
+}
+
+// manageEvent moves the event through its stages: registration open,
+// countdown, running, paused and closed.
+func manageEvent(reader *bufio.Reader) {
+	blue := color.New(color.FgBlue).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	event, err := getEventFromFirebase()
+	if err != nil {
+		fmt.Println(red(fmt.Sprintf("Error getting event: %v", err)))
+		return
+	}
+	fmt.Println("Event:", eventText(event))
+	fmt.Println("1. Open registration (teams wait in the lobby)")
+	fmt.Println("2. Start a countdown")
+	fmt.Println("3. Start now")
+	fmt.Println("4. Pause (also pauses games in play)")
+	fmt.Println("5. Resume")
+	fmt.Println("6. Close")
+	fmt.Println("7. Stop running an event")
+	fmt.Print(green("Choose an option: "))
+	choice, _ := reader.ReadString('\n')
+
+	state := ""
+	startsAt := time.Now()
+	switch strings.TrimSpace(choice) {
+	case "1":
+		state = eventOpen
+	case "2":
+		fmt.Print(green("Enter how many seconds to count down from: "))
+		secondsStr, _ := reader.ReadString('\n')
+		seconds, err := strconv.Atoi(strings.TrimSpace(secondsStr))
+		if err != nil || seconds < 1 {
+			fmt.Println(red("Invalid input. Please enter a number."))
+			return
+		}
+		state = eventCountdown
+		startsAt = startsAt.Add(time.Duration(seconds) * time.Second)
+	case "3", "5":
+		state = eventRunning
+	case "4":
+		state = eventPaused
+	case "6":
+		state = eventClosed
+	case "7":
+	default:
+		fmt.Println(red("Invalid option"))
+		return
+	}
+
+	if err := setEventInFirebase(state, startsAt); err != nil {
+		fmt.Println(red(fmt.Sprintf("Error setting event: %v", err)))
+		return
+	}
+	fmt.Println(blue(fmt.Sprintf("Event: %s\n", eventText(Event{State: state, StartsAt: startsAt}))))
+}
+
+// sendAnnouncementInFirebase posts a message for every game terminal and
+// the leaderboard to show. It is stamped with the server's time, which the
+// game uses to pick up new announcements.
+func sendAnnouncementInFirebase(text string) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, _, err = client.Collection("announcements").Add(ctx, map[string]interface{}{
+		"text":   text,
+		"sentAt": firestore.ServerTimestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("error sending announcement to Firebase: %v", err)
+	}
+
+	return nil
+}
+
+// controlDoc is where the admin's controls for a team are kept, or those
+// for everyone if teamName is empty.
+func controlDoc(client *firestore.Client, teamName string) *firestore.DocumentRef {
+	if teamName == "" {
+		return client.Collection("game_settings").Doc("control")
+	}
+	return client.Collection("team_controls").Doc(teamName)
+}
+
+// setPausedInFirebase pauses or resumes the games of a team, or of
+// everyone if teamName is empty. Resuming everyone resumes teams paused
+// on their own too.
+func setPausedInFirebase(teamName string, paused bool) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = controlDoc(client, teamName).Set(ctx, map[string]interface{}{"paused": paused}, firestore.MergeAll)
+	if err != nil {
+		return fmt.Errorf("error setting pause in Firebase: %v", err)
+	}
+	if teamName != "" || paused {
+		return nil
+	}
+
+	docs, err := client.Collection("team_controls").Where("paused", "==", true).Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("error retrieving team controls: %v", err)
+	}
+	for _, doc := range docs {
+		if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "paused", Value: false}}); err != nil {
+			return fmt.Errorf("error resuming team %s: %v", doc.Ref.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// grantExtraTimeInFirebase gives the games in play of a team, or of
+// everyone if teamName is empty, seconds more on the clock.
+func grantExtraTimeInFirebase(teamName string, seconds int) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	_, err = controlDoc(client, teamName).Set(ctx, map[string]interface{}{"extraSeconds": firestore.Increment(seconds)}, firestore.MergeAll)
+	if err != nil {
+		return fmt.Errorf("error granting extra time in Firebase: %v", err)
+	}
+
+	return nil
+}
+
+// controlGames pauses, resumes or extends the games in play, for everyone
+// or for one team.
+func controlGames(reader *bufio.Reader) {
+	blue := color.New(color.FgBlue).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	// Racing teams share the race's clock, which the game server keeps
+	// running for all of them
+	if racing, err := getRaceModeFromFirebase(); err != nil {
+		fmt.Println(red(fmt.Sprintf("Error getting race mode: %v", err)))
+	} else if racing {
+		fmt.Println(yellow("Race mode is on. Teams racing on the game server share one clock, so their games can't be paused or given extra time; only web games and resumed games will follow these controls."))
+	}
+
+	fmt.Println("1. Pause")
+	fmt.Println("2. Resume")
+	fmt.Println("3. Give extra time")
+	fmt.Print(green("Choose an option: "))
+	choice, _ := reader.ReadString('\n')
+	choice = strings.TrimSpace(choice)
+	if choice != "1" && choice != "2" && choice != "3" {
+		fmt.Println(red("Invalid option"))
+		return
+	}
+
+	fmt.Print(green("Enter the team name (or leave blank for every team): "))
+	teamName, _ := reader.ReadString('\n')
+	teamName = strings.TrimSpace(strings.ToLower(teamName))
+	who := "every team"
+	if teamName != "" {
+		who = "team " + teamName
+	}
+
+	switch choice {
+	case "1", "2":
+		paused := choice == "1"
+		if err := setPausedInFirebase(teamName, paused); err != nil {
+			fmt.Println(red(fmt.Sprintf("Error pausing games: %v", err)))
+		} else if paused {
+			fmt.Println(blue(fmt.Sprintf("Paused the games of %s.\n", who)))
+		} else {
+			fmt.Println(blue(fmt.Sprintf("Resumed the games of %s.\n", who)))
+		}
+	case "3":
+		fmt.Print(green("Enter how many seconds to give: "))
+		secondsStr, _ := reader.ReadString('\n')
+		seconds, err := strconv.Atoi(strings.TrimSpace(secondsStr))
+		if err != nil || seconds < 1 {
+			fmt.Println(red("Invalid input. Please enter a number."))
+			return
+		}
+		if err := grantExtraTimeInFirebase(teamName, seconds); err != nil {
+			fmt.Println(red(fmt.Sprintf("Error granting extra time: %v", err)))
+		} else {
+			fmt.Println(blue(fmt.Sprintf("Gave %s %s more.\n", who, formatDuration(time.Duration(seconds)*time.Second))))
+		}
+	}
+}
+
+// SessionEvent is something that happened in a game in play, as the game
+// records it for judges to watch.
+type SessionEvent struct {
+	Type     string `firestore:"type"`
+	Riddle   int    `firestore:"riddle"`
+	Question string `firestore:"question"`
+	Guess    string `firestore:"guess"`
+	Correct  bool   `firestore:"correct"`
+	Text     string `firestore:"text"` // The hint, round name or end reason
+
+	Score     int       `firestore:"score"`
+	LivesLeft int       `firestore:"livesLeft"`
+	Remaining float64   `firestore:"remaining"` // Seconds left on the clock
+	Paused    bool      `firestore:"paused"`
+	At        time.Time `firestore:"at"`
+}
+
+// getLatestSessionFromFirebase returns the document of the team's most
+// recent attempt, or nil if it hasn't played.
+func getLatestSessionFromFirebase(client *firestore.Client, teamName string) (*firestore.DocumentRef, Session, error) {
+	docs, err := client.Collection("sessions").Where("team", "==", teamName).Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, Session{}, fmt.Errorf("error retrieving sessions: %v", err)
+	}
+
+	var latest *firestore.DocumentRef
+	var session Session
+	for _, doc := range docs {
+		var s Session
+		if err := doc.DataTo(&s); err != nil {
+			return nil, Session{}, fmt.Errorf("error parsing session data: %v", err)
+		}
+		if latest == nil || s.Attempt > session.Attempt {
+			latest, session = doc.Ref, s
+		}
+	}
+	return latest, session, nil
+}
+
+// describeEvent is one line of a watched game.
+func describeEvent(event SessionEvent) string {
+	blue := color.New(color.FgBlue).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	var text string
+	switch event.Type {
+	case "asked":
+		text = fmt.Sprintf("%s %s", green(fmt.Sprintf("Riddle %d:", event.Riddle)), event.Question)
+	case "answered":
+		if event.Correct {
+			text = blue(fmt.Sprintf("Answered %q: correct", event.Guess))
+		} else {
+			text = red(fmt.Sprintf("Answered %q: wrong", event.Guess))
+		}
+	case "hint":
+		text = yellow(fmt.Sprintf("Took a hint: %s", event.Text))
+	case "passed":
+		text = yellow(fmt.Sprintf("Passed riddle %d for later", event.Riddle))
+	case "skipped":
+		text = red(fmt.Sprintf("Skipped riddle %d", event.Riddle))
+	case "round":
+		text = yellow(fmt.Sprintf("=== %s ===", event.Text))
+	case "paused":
+		text = yellow("Paused by the admin")
+	case "resumed":
+		text = yellow("Resumed")
+	case "extended":
+		text = yellow(fmt.Sprintf("Given %s more", event.Text))
+	case "ended":
+		text = blue(fmt.Sprintf("Game over: %s", event.Text))
+	default:
+		text = event.Type
+	}
+	return fmt.Sprintf("[%s] %s", event.At.Local().Format("15:04:05"), text)
+}
+
+// watchStatus is the team's standing as of the last event, with the clock
+// run on to now.
+func watchStatus(last SessionEvent) string {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	switch {
+	case last.At.IsZero():
+		return "Waiting for the team to play..."
+	case last.Type == "ended":
+		return fmt.Sprintf("%s %d  %s %d", yellow("Final score:"), last.Score, yellow("Lives left:"), last.LivesLeft)
+	}
+
+	remaining := last.Remaining
+	if !last.Paused {
+		remaining = max(remaining-time.Since(last.At).Seconds(), 0)
+	}
+	status := fmt.Sprintf("%s %d  %s %d  %s %s", yellow("Score:"), last.Score, yellow("Lives left:"), last.LivesLeft,
+		yellow("Time left:"), formatDuration(time.Duration(remaining*float64(time.Second))))
+	if last.Paused {
+		status += yellow("  (paused)")
+	}
+	return status
+}
+
+// watchTeam follows the team's latest game as it is played, read-only,
+// until stop is closed: every riddle asked, answer given and hint taken,
+// and the team's score, lives and time left.
+func watchTeam(teamName string, stop <-chan struct{}) {
+	blue := color.New(color.FgBlue).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		fmt.Println(red(fmt.Sprintf("Error creating Firestore client: %v", err)))
+		return
+	}
+	defer client.Close()
+
+	ref, session, err := getLatestSessionFromFirebase(client, teamName)
+	if err != nil {
+		fmt.Println(red(fmt.Sprintf("Error finding the team's game: %v", err)))
+		return
+	}
+	if ref == nil {
+		fmt.Println(red(fmt.Sprintf("Team %s hasn't played yet.", teamName)))
+		return
+	}
+	fmt.Println(blue(fmt.Sprintf("\nWatching team %s, attempt %d. Press Enter to stop.\n", teamName, session.Attempt)))
+
+	// The game's events so far come in the first snapshot, then each one
+	// as it happens
+	events := make(chan SessionEvent)
+	errs := make(chan error, 1)
+	go func() {
+		snapshots := ref.Collection("events").OrderBy("at", firestore.Asc).Snapshots(ctx)
+		defer snapshots.Stop()
+		for {
+			snapshot, err := snapshots.Next()
+			if err != nil {
+				if ctx.Err() == nil {
+					errs <- err
+				}
+				return
+			}
+			for _, change := range snapshot.Changes {
+				if change.Kind != firestore.DocumentAdded {
+					continue
+				}
+				var event SessionEvent
+				if err := change.Doc.DataTo(&event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	// Events are printed above a status line that is redrawn every second
+	var last SessionEvent
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		fmt.Print("\r\033[K" + watchStatus(last))
+		select {
+		case <-stop:
+			fmt.Println()
+			return
+		case err := <-errs:
+			fmt.Println()
+			fmt.Println(red(fmt.Sprintf("Error watching the game: %v", err)))
+			return
+		case event := <-events:
+			fmt.Print("\r\033[K")
+			fmt.Println(describeEvent(event))
+			last = event
+		case <-ticker.C:
+		}
+	}
+}
+
+// untilEnter returns a channel that is closed once Enter is pressed.
+func untilEnter(reader *bufio.Reader) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		reader.ReadString('\n')
+		close(done)
+	}()
+	return done
+}
+
+func developerInterface() {
+	reader := bufio.NewReader(os.Stdin)
+	blue := color.New(color.FgBlue).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	displayLogo()
+
+	for {
+		fmt.Println(blue("  Developer CLI\n"))
+		fmt.Println("1. View Teams")
+		fmt.Println("2. Add Riddle")
+		fmt.Println("3. Change admin Password")
+		fmt.Println("4. Add Approved Team")
+		fmt.Println("5. View Approved Teams")
+		fmt.Println("6. Set Game Duration")
+		fmt.Println("7. Delete All Riddles")
+		fmt.Println("8. View All Riddles") // New option
+		fmt.Println("9. View Session Results")
+		fmt.Println("10. Set Riddle Strictness")
+		fmt.Println("11. Edit Riddle Answers")
+		fmt.Println("12. Set Accent Matching")
+		fmt.Println("13. Edit Riddle Hints")
+		fmt.Println("14. Set Hint Cost")
+		fmt.Println("15. Set Skip Penalty")
+		fmt.Println("16. Set Riddle Difficulty")
+		fmt.Println("17. Set Difficulty Mix")
+		fmt.Println("18. Set Riddle Category")
+		fmt.Println("19. Configure Themed Rounds")
+		fmt.Println("20. Set Competition Mode")
+		fmt.Println("21. Set Repeat Riddle Policy")
+		fmt.Println("22. Clear Team Riddle History")
+		fmt.Println("23. Set Answer Reveal Policy")
+		fmt.Println("24. Set Attempt Policy")
+		fmt.Println("25. Set Resume Rules")
+		fmt.Println("26. Set Ranking Tiebreakers")
+		fmt.Println("27. Show Live Leaderboard")
+		fmt.Println("28. Set Race Mode")
+		fmt.Println("29. Manage Event")
+		fmt.Println("30. Pause, Resume or Extend Games")
+		fmt.Println("31. Send Announcement")
+		fmt.Println("32. Watch a Team")
+		fmt.Println("33. Exit")
+		fmt.Print(green("Choose an option: "))
+
+		var choice int
+		fmt.Scanln(&choice)
+
+		switch choice {
+		case 1:
+			viewTeamsInFirebase()
+			fmt.Println()
+		case 2:
+			fmt.Print(green("Enter the riddle question: "))
+			question, _ := reader.ReadString('\n')
+			question = strings.TrimSpace(question)
+
+			fmt.Print(green("Enter the riddle answer: "))
 			answer, _ := reader.ReadString('\n')
 			answer = strings.TrimSpace(answer)
 
+			aliases := readAliases(reader)
+			patterns := readPatterns(reader)
+			hints := readHints(reader)
+
+			strictness, ok := readStrictness(reader)
+			if !ok {
+				continue
+			}
+			difficulty, ok := readDifficulty(reader)
+			if !ok {
+				continue
+			}
+
+			fmt.Print(green("Enter the riddle category (optional): "))
+			category, _ := reader.ReadString('\n')
+			category = strings.TrimSpace(strings.ToLower(category))
+
 			riddle := Riddle{
-				Question: question,
-				Answer:   answer,
+				Question:   question,
+				Answer:     answer,
+				Strictness: strictness,
+				Aliases:    aliases,
+				Patterns:   patterns,
+				Hints:      hints,
+				Difficulty: difficulty,
+				Category:   category,
 			}
 
 			addRiddleToFirebase(riddle)
@@ -372,6 +1755,485 @@ func developerInterface() {
 		case 8:
 			viewRiddlesInFirebase() // New case to view all riddles
 		case 9:
+			fmt.Print(green("Enter a team name (leave blank for all teams): "))
+			teamName, _ := reader.ReadString('\n')
+			teamName = strings.TrimSpace(strings.ToLower(teamName))
+
+			viewSessionsInFirebase(teamName)
+		case 10:
+			id, riddle, ok := chooseRiddle(reader)
+			if !ok {
+				continue
+			}
+			strictness, ok := readStrictness(reader)
+			if !ok {
+				continue
+			}
+			riddle.Strictness = strictness
+
+			err := updateRiddleInFirebase(id, riddle)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting strictness: %v", err)))
+			} else {
+				fmt.Println(blue("Riddle strictness updated successfully!\n"))
+			}
+		case 11:
+			id, riddle, ok := chooseRiddle(reader)
+			if !ok {
+				continue
+			}
+			fmt.Printf(green("Current answer: ")+"%s\n", riddle.Answer)
+			fmt.Printf(green("Also accepted: ")+"%s\n", strings.Join(riddle.Aliases, ", "))
+			fmt.Printf(green("Patterns: ")+"%s\n", strings.Join(riddle.Patterns, "  "))
+
+			fmt.Print(green("Enter the new main answer (leave blank to keep it): "))
+			answer, _ := reader.ReadString('\n')
+			answer = strings.TrimSpace(answer)
+			if answer != "" {
+				riddle.Answer = answer
+			}
+			riddle.Aliases = readAliases(reader)
+			riddle.Patterns = readPatterns(reader)
+
+			err := updateRiddleInFirebase(id, riddle)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error updating riddle answers: %v", err)))
+			} else {
+				fmt.Println(blue("Riddle answers updated successfully!\n"))
+			}
+		case 12:
+			fmt.Print(green("Should accented letters match plain ones, e.g. \"cafe\" for \"café\"? (y/n): "))
+			confirmation, _ := reader.ReadString('\n')
+			confirmation = strings.TrimSpace(strings.ToLower(confirmation))
+
+			err := setIgnoreAccentsInFirebase(confirmation == "y")
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting accent matching: %v", err)))
+			} else {
+				fmt.Println(blue("Accent matching set successfully!\n"))
+			}
+		case 13:
+			id, riddle, ok := chooseRiddle(reader)
+			if !ok {
+				continue
+			}
+			for i, hint := range riddle.Hints {
+				fmt.Printf(green("Current hint %d: ")+"%s\n", i+1, hint)
+			}
+			riddle.Hints = readHints(reader)
+
+			err := updateRiddleInFirebase(id, riddle)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error updating riddle hints: %v", err)))
+			} else {
+				fmt.Println(blue("Riddle hints updated successfully!\n"))
+			}
+		case 14:
+			fmt.Print(green("Enter the points taken off for each hint: "))
+			costStr, _ := reader.ReadString('\n')
+			cost, err := strconv.Atoi(strings.TrimSpace(costStr))
+			if err != nil || cost < 0 {
+				fmt.Println(red("Invalid input. Please enter a whole number of points."))
+				continue
+			}
+			err = setHintCostInFirebase(cost)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting hint cost: %v", err)))
+			} else {
+				fmt.Println(blue("Hint cost set successfully!\n"))
+			}
+		case 15:
+			fmt.Print(green("Enter the points taken off for skipping a riddle: "))
+			penaltyStr, _ := reader.ReadString('\n')
+			penalty, err := strconv.Atoi(strings.TrimSpace(penaltyStr))
+			if err != nil || penalty < 0 {
+				fmt.Println(red("Invalid input. Please enter a whole number of points."))
+				continue
+			}
+			err = setSkipPenaltyInFirebase(penalty)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting skip penalty: %v", err)))
+			} else {
+				fmt.Println(blue("Skip penalty set successfully!\n"))
+			}
+		case 16:
+			id, riddle, ok := chooseRiddle(reader)
+			if !ok {
+				continue
+			}
+			difficulty, ok := readDifficulty(reader)
+			if !ok {
+				continue
+			}
+			riddle.Difficulty = difficulty
+
+			err := updateRiddleInFirebase(id, riddle)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting difficulty: %v", err)))
+			} else {
+				fmt.Println(blue("Riddle difficulty updated successfully!\n"))
+			}
+		case 17:
+			fmt.Println(blue("Enter how many riddles of each difficulty a game should have. Use 0 for all to pick 15 at random."))
+			mix := make(map[string]int)
+			valid := true
+			for _, difficulty := range difficulties {
+				fmt.Print(green(fmt.Sprintf("Number of %s riddles: ", difficulty)))
+				countStr, _ := reader.ReadString('\n')
+				count, err := strconv.Atoi(strings.TrimSpace(countStr))
+				if err != nil || count < 0 {
+					fmt.Println(red("Invalid input. Please enter a whole number."))
+					valid = false
+					break
+				}
+				mix[difficulty] = count
+			}
+			if !valid {
+				continue
+			}
+
+			fmt.Print(green("Order riddles from easy to hard? (y/n): "))
+			rampUpStr, _ := reader.ReadString('\n')
+			rampUp := strings.TrimSpace(strings.ToLower(rampUpStr)) == "y"
+
+			err := setDifficultyMixInFirebase(mix, rampUp)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting difficulty mix: %v", err)))
+			} else {
+				fmt.Println(blue("Difficulty mix set successfully!\n"))
+			}
+		case 18:
+			id, riddle, ok := chooseRiddle(reader)
+			if !ok {
+				continue
+			}
+			fmt.Print(green("Enter the riddle category (leave blank for none): "))
+			category, _ := reader.ReadString('\n')
+			riddle.Category = strings.TrimSpace(strings.ToLower(category))
+
+			err := updateRiddleInFirebase(id, riddle)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting category: %v", err)))
+			} else {
+				fmt.Println(blue("Riddle category updated successfully!\n"))
+			}
+		case 19:
+			fmt.Println(blue("Enter the rounds in the order they are played. Leave the name blank to finish; with no rounds the game is a single set of random riddles."))
+			var rounds []map[string]interface{}
+			for {
+				fmt.Print(green(fmt.Sprintf("Round %d name: ", len(rounds)+1)))
+				name, _ := reader.ReadString('\n')
+				name = strings.TrimSpace(name)
+				if name == "" {
+					break
+				}
+
+				fmt.Print(green("Category (leave blank for any): "))
+				category, _ := reader.ReadString('\n')
+
+				fmt.Print(green("Number of riddles: "))
+				countStr, _ := reader.ReadString('\n')
+				count, err := strconv.Atoi(strings.TrimSpace(countStr))
+				if err != nil || count < 1 {
+					fmt.Println(red("Invalid input. Please enter a number. Round not added."))
+					continue
+				}
+
+				fmt.Print(green("Duration in minutes: "))
+				minutesStr, _ := reader.ReadString('\n')
+				minutes, err := strconv.Atoi(strings.TrimSpace(minutesStr))
+				if err != nil || minutes < 1 {
+					fmt.Println(red("Invalid input. Please enter a number. Round not added."))
+					continue
+				}
+
+				rounds = append(rounds, map[string]interface{}{
+					"name":     name,
+					"category": strings.TrimSpace(strings.ToLower(category)),
+					"riddles":  count,
+					"minutes":  minutes,
+				})
+			}
+
+			err := setRoundsInFirebase(rounds)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting rounds: %v", err)))
+			} else {
+				fmt.Println(blue("Rounds set successfully!\n"))
+			}
+		case 20:
+			fmt.Print(green("Should every team get the same riddles? (y/n): "))
+			enabled, _ := reader.ReadString('\n')
+			if strings.TrimSpace(strings.ToLower(enabled)) != "y" {
+				err := setCompetitionInFirebase(0, nil, false)
+				if err != nil {
+					fmt.Println(red(fmt.Sprintf("Error setting competition mode: %v", err)))
+				} else {
+					fmt.Println(blue("Competition mode turned off. Each team gets its own random riddles.\n"))
+				}
+				continue
+			}
+
+			var seed int64
+			var riddleIDs []string
+			fmt.Print(green("Pick riddles by a seed number or from a list? (seed/list): "))
+			mode, _ := reader.ReadString('\n')
+			if strings.TrimSpace(strings.ToLower(mode)) == "list" {
+				ids, riddles, err := getRiddlesFromFirebase()
+				if err != nil {
+					fmt.Println(red(fmt.Sprintf("Error fetching riddles: %v", err)))
+					continue
+				}
+				for i, riddle := range riddles {
+					fmt.Printf("%d. %s "+green("(%s)")+"\n", i+1, riddle.Question, riddle.Answer)
+				}
+				fmt.Print(green("Enter the riddle numbers in the order to ask them, separated by commas: "))
+				numbers, _ := reader.ReadString('\n')
+				for _, numberStr := range strings.Split(numbers, ",") {
+					number, err := strconv.Atoi(strings.TrimSpace(numberStr))
+					if err != nil || number < 1 || number > len(ids) {
+						fmt.Println(red(fmt.Sprintf("Ignoring invalid riddle number %q.", strings.TrimSpace(numberStr))))
+						continue
+					}
+					riddleIDs = append(riddleIDs, ids[number-1])
+				}
+				if len(riddleIDs) == 0 {
+					fmt.Println(red("No riddles chosen. Competition mode unchanged."))
+					continue
+				}
+			} else {
+				fmt.Print(green("Enter the event seed (leave blank to generate one): "))
+				seedStr, _ := reader.ReadString('\n')
+				seedStr = strings.TrimSpace(seedStr)
+				if seedStr == "" {
+					seed = time.Now().UnixNano()
+				} else {
+					parsed, err := strconv.ParseInt(seedStr, 10, 64)
+					if err != nil || parsed == 0 {
+						fmt.Println(red("Invalid input. Please enter a non-zero number."))
+						continue
+					}
+					seed = parsed
+				}
+			}
+
+			fmt.Print(green("Should every team also get the riddles in the same order? (y/n): "))
+			sameOrder, _ := reader.ReadString('\n')
+
+			err := setCompetitionInFirebase(seed, riddleIDs, strings.TrimSpace(strings.ToLower(sameOrder)) == "y")
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting competition mode: %v", err)))
+			} else if seed != 0 {
+				fmt.Printf(blue("Competition mode set with seed")+" %d\n\n", seed)
+			} else {
+				fmt.Println(blue("Competition mode set with the chosen riddles.\n"))
+			}
+		case 21:
+			fmt.Println("exclude - never serve a team riddles it has already seen")
+			fmt.Println("fill    - serve new riddles first, topping up with seen ones")
+			fmt.Println("allow   - ignore what teams have seen before")
+			fmt.Print(green("Enter the repeat policy: "))
+			policy, _ := reader.ReadString('\n')
+			policy = strings.TrimSpace(strings.ToLower(policy))
+			if policy != "exclude" && policy != "fill" && policy != "allow" {
+				fmt.Println(red("Invalid policy. Use exclude, fill or allow."))
+				continue
+			}
+
+			err := setRepeatPolicyInFirebase(policy)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting repeat policy: %v", err)))
+			} else {
+				fmt.Println(blue("Repeat policy set successfully!\n"))
+			}
+		case 22:
+			fmt.Print(green("Enter the team name: "))
+			teamName, _ := reader.ReadString('\n')
+			teamName = strings.TrimSpace(strings.ToLower(teamName))
+
+			err := clearSeenRiddlesInFirebase(teamName)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error clearing riddle history: %v", err)))
+			} else {
+				fmt.Println(blue("Riddle history cleared successfully!\n"))
+			}
+		case 23:
+			fmt.Println("immediate - show the correct answer right after a wrong guess or skip")
+			fmt.Println("summary   - show correct answers only on the end-of-game summary")
+			fmt.Println("never     - never show teams the answers they missed")
+			fmt.Print(green("Enter the reveal policy: "))
+			policy, _ := reader.ReadString('\n')
+			policy = strings.TrimSpace(strings.ToLower(policy))
+			if policy != "immediate" && policy != "summary" && policy != "never" {
+				fmt.Println(red("Invalid policy. Use immediate, summary or never."))
+				continue
+			}
+
+			err := setRevealPolicyInFirebase(policy)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting reveal policy: %v", err)))
+			} else {
+				fmt.Println(blue("Reveal policy set successfully!\n"))
+			}
+		case 24:
+			fmt.Print(green("Enter the number of attempts each team gets (1 disallows re-attempts, 0 for no limit): "))
+			maxStr, _ := reader.ReadString('\n')
+			maxAttempts, err := strconv.Atoi(strings.TrimSpace(maxStr))
+			if err != nil || maxAttempts < 0 {
+				fmt.Println(red("Invalid input. Please enter a whole number."))
+				continue
+			}
+
+			fmt.Println("latest  - a team's score is its most recent attempt")
+			fmt.Println("best    - a team's score is its best attempt")
+			fmt.Println("average - a team's score is the average of its attempts")
+			fmt.Print(green("Enter the scoring policy: "))
+			scoring, _ := reader.ReadString('\n')
+			scoring = strings.TrimSpace(strings.ToLower(scoring))
+			if scoring != ranking.ScoreLatest && scoring != ranking.ScoreBest && scoring != ranking.ScoreAverage {
+				fmt.Println(red("Invalid policy. Use latest, best or average."))
+				continue
+			}
+
+			err = setAttemptPolicyInFirebase(maxAttempts, scoring)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting attempt policy: %v", err)))
+			} else {
+				fmt.Println(blue("Attempt policy set successfully!\n"))
+			}
+		case 25:
+			fmt.Print(green("Can teams resume a game that was interrupted? (y/n): "))
+			enabled, _ := reader.ReadString('\n')
+			if strings.TrimSpace(strings.ToLower(enabled)) != "y" {
+				err := setResumeRulesInFirebase(false, 0)
+				if err != nil {
+					fmt.Println(red(fmt.Sprintf("Error setting resume rules: %v", err)))
+				} else {
+					fmt.Println(blue("Resuming turned off. Interrupted games count as abandoned.\n"))
+				}
+				continue
+			}
+
+			fmt.Print(green("Enter how many minutes after an interruption a game can be resumed: "))
+			graceStr, _ := reader.ReadString('\n')
+			grace, err := strconv.Atoi(strings.TrimSpace(graceStr))
+			if err != nil || grace < 1 {
+				fmt.Println(red("Invalid input. Please enter a number."))
+				continue
+			}
+
+			err = setResumeRulesInFirebase(true, grace)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting resume rules: %v", err)))
+			} else {
+				fmt.Println(blue("Resume rules set successfully!\n"))
+			}
+		case 26:
+			fmt.Println("Teams on the same score are ordered by these, in the order you list them:")
+			fmt.Println("wrongGuesses - fewer wrong guesses")
+			fmt.Println("time         - less time spent answering")
+			fmt.Println("hints        - fewer hints used")
+			fmt.Println("finish       - finished earlier")
+			fmt.Print(green("Enter the tiebreakers separated by commas (or 'none' to let tied teams share a rank): "))
+			input, _ := reader.ReadString('\n')
+			input = strings.TrimSpace(input)
+
+			tiebreakers := []string{}
+			valid := true
+			if !strings.EqualFold(input, "none") {
+				for _, tiebreaker := range strings.Split(input, ",") {
+					tiebreaker = strings.TrimSpace(tiebreaker)
+					switch strings.ToLower(tiebreaker) {
+					case strings.ToLower(ranking.TiebreakWrongGuesses):
+						tiebreakers = append(tiebreakers, ranking.TiebreakWrongGuesses)
+					case ranking.TiebreakTime, ranking.TiebreakHints, ranking.TiebreakFinish:
+						tiebreakers = append(tiebreakers, strings.ToLower(tiebreaker))
+					default:
+						valid = false
+					}
+				}
+			}
+			if !valid {
+				fmt.Println(red("Invalid tiebreaker. Use wrongGuesses, time, hints or finish."))
+				continue
+			}
+
+			err := setTiebreakersInFirebase(tiebreakers)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting tiebreakers: %v", err)))
+			} else {
+				fmt.Println(blue("Tiebreakers set successfully!\n"))
+			}
+		case 27:
+			showLeaderboard(reader)
+		case 28:
+			fmt.Print(green("Should teams on the game server race each other? (y/n): "))
+			enabled, _ := reader.ReadString('\n')
+			if strings.TrimSpace(strings.ToLower(enabled)) != "y" {
+				err := setRaceModeInFirebase(false, 2, 10, 60)
+				if err != nil {
+					fmt.Println(red(fmt.Sprintf("Error setting race mode: %v", err)))
+				} else {
+					fmt.Println(blue("Race mode turned off. Teams play solo against the clock.\n"))
+				}
+				continue
+			}
+
+			fmt.Print(green("Enter how many teams race each other: "))
+			teamsStr, _ := reader.ReadString('\n')
+			teams, err := strconv.Atoi(strings.TrimSpace(teamsStr))
+			if err != nil || teams < 2 {
+				fmt.Println(red("Invalid input. A race needs at least 2 teams."))
+				continue
+			}
+
+			fmt.Print(green("Enter how many riddles a race has: "))
+			riddlesStr, _ := reader.ReadString('\n')
+			riddles, err := strconv.Atoi(strings.TrimSpace(riddlesStr))
+			if err != nil || riddles < 1 {
+				fmt.Println(red("Invalid input. Please enter a number."))
+				continue
+			}
+
+			fmt.Print(green("Enter how many seconds teams have to solve each riddle: "))
+			secondsStr, _ := reader.ReadString('\n')
+			seconds, err := strconv.Atoi(strings.TrimSpace(secondsStr))
+			if err != nil || seconds < 1 {
+				fmt.Println(red("Invalid input. Please enter a number."))
+				continue
+			}
+
+			err = setRaceModeInFirebase(true, teams, riddles, seconds)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error setting race mode: %v", err)))
+			} else {
+				fmt.Println(blue("Race mode set successfully!\n"))
+			}
+		case 29:
+			manageEvent(reader)
+		case 30:
+			controlGames(reader)
+		case 31:
+			fmt.Print(green("Enter the announcement for every team: "))
+			text, _ := reader.ReadString('\n')
+			text = strings.TrimSpace(text)
+			if text == "" {
+				fmt.Println(red("The announcement can't be empty."))
+				continue
+			}
+
+			err := sendAnnouncementInFirebase(text)
+			if err != nil {
+				fmt.Println(red(fmt.Sprintf("Error sending announcement: %v", err)))
+			} else {
+				fmt.Println(blue("Announcement sent!\n"))
+			}
+		case 32:
+			fmt.Print(green("Enter the team name to watch: "))
+			teamName, _ := reader.ReadString('\n')
+			teamName = strings.TrimSpace(strings.ToLower(teamName))
+			watchTeam(teamName, untilEnter(reader))
+		case 33:
 			fmt.Println(blue("Exiting..."))
 			return
 		default:
@@ -381,6 +2243,13 @@ func developerInterface() {
 }
 
 func main() {
-	initFirebase()       // Initialize Firebase
+	initFirebase()   // Initialize Firebase
+	handleShutdown() // Exit cleanly on Ctrl-C
+
+	// "watch <team>" only follows a team's game, for a judge's screen
+	if len(os.Args) == 3 && os.Args[1] == "watch" {
+		watchTeam(strings.ToLower(os.Args[2]), untilEnter(bufio.NewReader(os.Stdin)))
+		return
+	}
 	developerInterface() // Run developer interface
 }
@@ -2,19 +2,42 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"maps"
+	"math"
 	"math/rand"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode"
 
 	"cloud.google.com/go/firestore"
 	"github.com/fatih/color"
+	"golang.org/x/net/websocket"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
 
 	firebase "firebase.google.com/go"
 	"google.golang.org/api/option"
+
+	"game/ranking"
 )
 
 type Team struct {
@@ -22,11 +45,200 @@ type Team struct {
 	Score    int    `json:"score"`
 	Attempts int    `json:"attempts"`
 	Password string `json:"password"` // New field for team password
+
+	SeenRiddles []string `json:"seenRiddles"` // Keys of every riddle the team has been served
 }
 
 type Riddle struct {
-	Question string `json:"question"`
-	Answer   string `json:"answer"`
+	ID         string `json:"id" firestore:"id,omitempty"` // Firebase document ID; empty for hardcoded riddles
+	Question   string `json:"question"`
+	Answer     string `json:"answer"`
+	Strictness string `json:"strictness" firestore:"strictness"` // exact, normal or lenient; empty means normal
+
+	Aliases  []string `json:"aliases" firestore:"aliases"`   // Other accepted answers
+	Patterns []string `json:"patterns" firestore:"patterns"` // Regular expressions a whole guess may match
+	Hints    []string `json:"hints" firestore:"hints"`       // Revealed in order by the hint command
+
+	Difficulty string `json:"difficulty" firestore:"difficulty"` // easy, medium or hard; empty means medium
+	Category   string `json:"category" firestore:"category"`     // e.g. tech, wordplay, general knowledge
+}
+
+// Difficulty levels from easiest to hardest, and what a correct answer to
+// each is worth.
+var difficulties = []string{"easy", "medium", "hard"}
+
+var difficultyPoints = map[string]int{
+	"easy":   3,
+	"medium": 5,
+	"hard":   8,
+}
+
+// riddleDifficulty returns the riddle's difficulty, treating anything unset
+// or unknown as medium.
+func riddleDifficulty(riddle Riddle) string {
+	if _, ok := difficultyPoints[riddle.Difficulty]; ok {
+		return riddle.Difficulty
+	}
+	return "medium"
+}
+
+// riddleKey identifies a riddle in a team's history: its document ID, or the
+// question for hardcoded riddles.
+func riddleKey(riddle Riddle) string {
+	if riddle.ID != "" {
+		return riddle.ID
+	}
+	return riddle.Question
+}
+
+func riddlePoints(riddle Riddle) int {
+	return difficultyPoints[riddleDifficulty(riddle)]
+}
+
+// Strictness levels for matching a guess against a riddle's answer.
+const (
+	matchExact   = "exact"
+	matchNormal  = "normal"
+	matchLenient = "lenient"
+)
+
+// Reasons a game can end, recorded on the session.
+const (
+	endCompleted = "completed"
+	endHanged    = "hanged"
+	endTimedOut  = "timed_out"
+	endQuit      = "quit"
+)
+
+// A session is playing until it finishes. One left playing when its
+// terminal died, or aborted when the game was interrupted or terminated,
+// can be resumed for a while, then counts as abandoned.
+const (
+	statusPlaying  = "playing"
+	statusFinished = "finished"
+	statusAborted  = "aborted"
+
+	endAbandoned    = "abandoned"
+	endInterrupted  = "interrupted"
+	endTerminated   = "terminated"
+	endDisconnected = "disconnected"
+)
+
+// Session is a single play-through of the game by a team.
+type Session struct {
+	Team         string    `json:"team" firestore:"team"`
+	Attempt      int       `json:"attempt" firestore:"attempt"`
+	Status       string    `json:"status" firestore:"status"`
+	StartedAt    time.Time `json:"startedAt" firestore:"startedAt"`
+	UpdatedAt    time.Time `json:"updatedAt" firestore:"updatedAt"`
+	EndedAt      time.Time `json:"endedAt" firestore:"endedAt"`
+	EndReason    string    `json:"endReason" firestore:"endReason"`
+	Score        int       `json:"score" firestore:"score"`
+	WrongGuesses int       `json:"wrongGuesses" firestore:"wrongGuesses"`
+	Answered     int       `json:"answered" firestore:"answered"`
+	Total        int       `json:"total" firestore:"total"`
+	LivesLeft    int       `json:"livesLeft" firestore:"livesLeft"`
+	Rank         int       `json:"rank" firestore:"rank"`
+
+	// Seed picked the riddles; OrderSeed, when set, shuffled their order
+	// separately. Together they reproduce exactly what the team was served.
+	Seed      int64 `json:"seed" firestore:"seed"`
+	OrderSeed int64 `json:"orderSeed" firestore:"orderSeed"`
+
+	Results []QuestionResult `json:"results" firestore:"results"`
+	Rounds  []RoundResult    `json:"rounds" firestore:"rounds"`
+
+	Progress *Progress `json:"progress,omitempty" firestore:"progress,omitempty"` // Checkpoint of a game still in play
+
+	// The team's scores from earlier attempts and how they combine with
+	// this one, set when the game starts.
+	previousScores []int
+	scoring        string
+}
+
+// Progress is how far a game in play has got, checkpointed on the session
+// so the game can be resumed if it is interrupted.
+type Progress struct {
+	Riddles   []Riddle         `json:"riddles" firestore:"riddles"`     // Every riddle served, round after round
+	Rounds    []RoundPlan      `json:"rounds" firestore:"rounds"`       // How the riddles split into rounds
+	Themed    bool             `json:"themed" firestore:"themed"`       // Played as themed rounds rather than one set
+	Round     int              `json:"round" firestore:"round"`         // The round being played
+	Queue     []int            `json:"queue" firestore:"queue"`         // Riddles of the round still to ask, as indexes into Riddles
+	Deferred  []int            `json:"deferred" firestore:"deferred"`   // Riddles passed until the end of the round
+	FinalPass bool             `json:"finalPass" firestore:"finalPass"` // Whether the passed riddles are being asked
+	Results   []QuestionResult `json:"results" firestore:"results"`     // Work so far on each riddle
+	Remaining float64          `json:"remaining" firestore:"remaining"` // Seconds left on the round's clock
+
+	RoundStartScore    int `json:"roundStartScore" firestore:"roundStartScore"`
+	RoundStartAnswered int `json:"roundStartAnswered" firestore:"roundStartAnswered"`
+}
+
+// RoundPlan is one round of a game as it was laid out at the start.
+type RoundPlan struct {
+	Name    string  `json:"name" firestore:"name"`
+	Riddles int     `json:"riddles" firestore:"riddles"`
+	Seconds float64 `json:"seconds" firestore:"seconds"`
+}
+
+// RoundResult is how a team did in one themed round of a session.
+type RoundResult struct {
+	Name      string `json:"name" firestore:"name"`
+	EndReason string `json:"endReason" firestore:"endReason"`
+	Score     int    `json:"score" firestore:"score"`
+	Answered  int    `json:"answered" firestore:"answered"`
+	Total     int    `json:"total" firestore:"total"`
+}
+
+// QuestionResult is how a team did on one riddle of a session.
+type QuestionResult struct {
+	Question string  `json:"question" firestore:"question"`
+	Answer   string  `json:"answer" firestore:"answer"`
+	Guess    string  `json:"guess" firestore:"guess"`
+	Matched  string  `json:"matched" firestore:"matched"` // The accepted answer or pattern the guess matched
+	Correct  bool    `json:"correct" firestore:"correct"`
+	Seconds  float64 `json:"seconds" firestore:"seconds"`
+	Points   int     `json:"points" firestore:"points"`
+
+	HintsUsed int  `json:"hintsUsed" firestore:"hintsUsed"`
+	Passed    bool `json:"passed" firestore:"passed"`   // Put off until the end at least once
+	Skipped   bool `json:"skipped" firestore:"skipped"` // Given up without a guess
+}
+
+// Policies for serving riddles a team has already seen on an earlier attempt.
+const (
+	repeatExclude = "exclude" // Never serve them, even if the game comes up short
+	repeatFill    = "fill"    // Serve new riddles first, topping up with seen ones
+	repeatAllow   = "allow"   // Ignore the team's history
+)
+
+// How a team's standing is worked out from the scores of its attempts,
+// as the ranking names them.
+const (
+	scoreLatest  = ranking.ScoreLatest
+	scoreBest    = ranking.ScoreBest
+	scoreAverage = ranking.ScoreAverage
+)
+
+// When the correct answer to a missed riddle is shown to the team.
+const (
+	revealImmediately = "immediate" // Right after the wrong guess or skip
+	revealAtEnd       = "summary"   // Only on the end-of-game summary
+	revealNever       = "never"     // Not shown to the team at all
+)
+
+const (
+	riddlesPerGame     = 15
+	defaultHintCost    = 1
+	defaultSkipPenalty = 2
+	defaultResumeGrace = 10 * time.Minute
+	defaultRaceTeams   = 2
+	defaultRaceRiddles = 10
+	defaultRaceTime    = time.Minute
+)
+
+// sessionID returns the document ID of a session, one per team attempt.
+func sessionID(team string, attempt int) string {
+	return fmt.Sprintf("%s_%d", team, attempt)
 }
 
 var riddles = []Riddle{
@@ -135,11 +347,28 @@ func getPasswordFromFirebase() (string, error) {
 	return password, nil
 }
 
+// writeMu lets shutdown wait for saves in flight and hold off new ones
+// while it makes its final save.
+var writeMu sync.RWMutex
+
+// beginWrite holds off shutdown until the write it guards is done. Call it
+// as defer beginWrite()().
+func beginWrite() func() {
+	writeMu.RLock()
+	return writeMu.RUnlock
+}
+
 func saveTeamToFirebase(team Team) {
+	defer beginWrite()()
+	writeTeamToFirebase(team)
+}
+
+func writeTeamToFirebase(team Team) {
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
-		log.Fatalf("Error creating Firestore client: %v\n", err)
+		log.Printf("Error creating Firestore client: %v\n", err)
+		return
 	}
 	defer client.Close()
 
@@ -148,10 +377,12 @@ func saveTeamToFirebase(team Team) {
 		"name":     team.Name,
 		"attempts": team.Attempts,
 		"password": team.Password, // Save password to Firestore
+
+		"seenRiddles": team.SeenRiddles,
 	}, firestore.MergeAll)
 
 	if err != nil {
-		log.Fatalf("Error updating team in Firebase: %v\n", err)
+		log.Printf("Error updating team in Firebase: %v\n", err)
 	}
 }
 
@@ -177,88 +408,100 @@ func getTeamFromFirebase(teamName string) (Team, error) {
 	return team, nil
 }
 
-func getApprovedTeamsFromFirebase() ([]string, error) {
+func saveSessionToFirebase(session Session) error {
+	defer beginWrite()()
+	return writeSessionToFirebase(session)
+}
+
+func writeSessionToFirebase(session Session) error {
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error creating Firestore client: %v", err)
+		return fmt.Errorf("error creating Firestore client: %v", err)
 	}
 	defer client.Close()
 
-	docs, err := client.Collection("approved_teams").Documents(ctx).GetAll()
+	_, err = client.Collection("sessions").Doc(sessionID(session.Team, session.Attempt)).Set(ctx, session)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving approved teams: %v", err)
-	}
-
-	var approvedTeams []string
-	for _, doc := range docs {
-		approvedTeams = append(approvedTeams, doc.Ref.ID)
+		return fmt.Errorf("error saving session: %v", err)
 	}
 
-	return approvedTeams, nil
+	return nil
 }
 
-func validateTeam(approvedTeams []string, teamName string) bool {
-	for _, approvedTeam := range approvedTeams {
-		if approvedTeam == teamName {
-			return true
-		}
+// touchSessionInFirebase marks the session with the given ID as just
+// updated, leaving the rest of it alone.
+func touchSessionInFirebase(id string) error {
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return fmt.Errorf("error creating Firestore client: %v", err)
 	}
-	return false
-}
+	defer client.Close()
 
-func createPasswordForNewTeam(team *Team, reader *bufio.Reader) {
-	green := color.New(color.FgGreen).SprintFunc()
+	_, err = client.Collection("sessions").Doc(id).Update(ctx, []firestore.Update{{Path: "updatedAt", Value: time.Now()}})
+	if err != nil {
+		return fmt.Errorf("error touching session: %v", err)
+	}
 
-	fmt.Print(green("This is your first login. Please create a password: "))
-	password, _ := reader.ReadString('\n')
-	password = strings.TrimSpace(password)
-	team.Password = password
-	saveTeamToFirebase(*team)
+	return nil
 }
 
-func validatePassword(team *Team, reader *bufio.Reader) bool {
-	green := color.New(color.FgGreen).SprintFunc()
+// What happened in a game, as told to judges watching it.
+const (
+	playAsked    = "asked"
+	playAnswered = "answered"
+	playHint     = "hint"
+	playPassed   = "passed"
+	playSkipped  = "skipped"
+	playRound    = "round"
+	playPaused   = "paused"
+	playResumed  = "resumed"
+	playExtended = "extended"
+	playEnded    = "ended"
+)
 
-	fmt.Print(green("Enter your password: "))
-	passwordEntered, _ := reader.ReadString('\n')
-	passwordEntered = strings.TrimSpace(passwordEntered)
-	return passwordEntered == team.Password
-}
+// SessionEvent is something that happened in a game in play, kept in the
+// session's events as it happens so judges can watch the game live from
+// the admin tool. Each carries the team's standing at the time.
+type SessionEvent struct {
+	Type     string `firestore:"type"`
+	Riddle   int    `firestore:"riddle"` // Number of the riddle it is about, from 1; 0 if none
+	Question string `firestore:"question"`
+	Guess    string `firestore:"guess"`
+	Correct  bool   `firestore:"correct"`
+	Text     string `firestore:"text"` // The hint, round name or end reason
 
-var timeUp = make(chan bool)
+	Score     int       `firestore:"score"`
+	LivesLeft int       `firestore:"livesLeft"`
+	Remaining float64   `firestore:"remaining"` // Seconds left on the clock
+	Paused    bool      `firestore:"paused"`
+	At        time.Time `firestore:"at"`
+}
 
-func startTimer(duration time.Duration) {
-	go func() {
-		time.Sleep(duration)
-		timeUp <- true
-	}()
+// saveSessionEventToFirebase adds event to the session with the given ID.
+func saveSessionEventToFirebase(id string, event SessionEvent) {
+	defer beginWrite()()
+	writeSessionEventToFirebase(id, event)
 }
 
-func getGameDurationFromFirebase() (time.Duration, error) {
+func writeSessionEventToFirebase(id string, event SessionEvent) {
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("error creating Firestore client: %v", err)
+		log.Printf("Error creating Firestore client: %v\n", err)
+		return
 	}
 	defer client.Close()
 
-	doc, err := client.Collection("game_settings").Doc("duration").Get(ctx)
+	_, _, err = client.Collection("sessions").Doc(id).Collection("events").Add(ctx, event)
 	if err != nil {
-		return 0, fmt.Errorf("error retrieving game duration: %v", err)
-	}
-
-	var data map[string]interface{}
-	doc.DataTo(&data)
-	minutes, ok := data["minutes"].(int64)
-	if !ok {
-		return 0, fmt.Errorf("invalid game duration format")
+		log.Printf("Error saving session event: %v\n", err)
 	}
-
-	return time.Duration(minutes) * time.Minute, nil
 }
 
-func getRiddlesFromFirebase() ([]Riddle, error) {
+func getTeamsFromFirebase() ([]Team, error) {
 	ctx := context.Background()
 	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
@@ -266,312 +509,3787 @@ func getRiddlesFromFirebase() ([]Riddle, error) {
 	}
 	defer client.Close()
 
-	var riddlesFromFirebase []Riddle
-	docs, err := client.Collection("riddles").Documents(ctx).GetAll()
+	docs, err := client.Collection("teams").Documents(ctx).GetAll()
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving riddles: %v", err)
+		return nil, fmt.Errorf("error retrieving teams: %v", err)
 	}
 
+	var teams []Team
 	for _, doc := range docs {
-		var riddle Riddle
-		err := doc.DataTo(&riddle)
-		if err != nil {
-			return nil, fmt.Errorf("error converting document data to riddle: %v", err)
+		var team Team
+		if err := doc.DataTo(&team); err != nil {
+			return nil, fmt.Errorf("error parsing team data: %v", err)
 		}
-		riddlesFromFirebase = append(riddlesFromFirebase, riddle)
+		teams = append(teams, team)
 	}
 
-	return riddlesFromFirebase, nil
+	return teams, nil
 }
 
-func randomRiddles(num int) ([]Riddle, error) {
-	firebaseRiddles, err := getRiddlesFromFirebase() // Fetch riddles from Firebase
+// getSessionScoresFromFirebase returns the scores of a team's sessions,
+// leaving out the given attempt.
+func getSessionScoresFromFirebase(teamName string, exceptAttempt int) ([]int, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error creating Firestore client: %v", err)
 	}
+	defer client.Close()
 
-	// Combine hardcoded riddles with Firebase riddles
-	allRiddles := append(riddles, firebaseRiddles...)
-
-	// Shuffle the combined list and pick the desired number of riddles
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(allRiddles), func(i, j int) {
-		allRiddles[i], allRiddles[j] = allRiddles[j], allRiddles[i]
-	})
+	docs, err := client.Collection("sessions").Where("team", "==", teamName).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving sessions: %v", err)
+	}
 
-	// Ensure we don't try to select more riddles than exist
-	if num > len(allRiddles) {
-		num = len(allRiddles)
+	var scores []int
+	for _, doc := range docs {
+		var session Session
+		if err := doc.DataTo(&session); err != nil {
+			return nil, fmt.Errorf("error parsing session data: %v", err)
+		}
+		if session.Attempt != exceptAttempt {
+			scores = append(scores, session.Score)
+		}
 	}
 
-	return allRiddles[:num], nil
+	return scores, nil
 }
 
-func drawHangman(stage int) {
-	fmt.Println(hangmanStages[stage])
+// combinedScore works out a team's score from its attempts under the
+// scoring policy, where current is the score of the latest attempt.
+func combinedScore(previous []int, current int, scoring string) int {
+	switch scoring {
+	case scoreBest:
+		return slices.Max(append([]int{current}, previous...))
+	case scoreAverage:
+		total := current
+		for _, score := range previous {
+			total += score
+		}
+		return int(math.Round(float64(total) / float64(len(previous)+1)))
+	default:
+		return current
+	}
 }
 
-func startScoreUpdater(team *Team) {
-	go func() {
-		for {
-			time.Sleep(1 * time.Second) // Update score every 1 second
-			saveTeamToFirebase(*team)
-		}
-	}()
+// teamsMu guards the teams in play, which their score updaters save from
+// goroutines of their own while the game changes them.
+var teamsMu sync.Mutex
+
+// teamCopy returns the team as it stands, for saving.
+func teamCopy(team *Team) Team {
+	teamsMu.Lock()
+	defer teamsMu.Unlock()
+	return *team
 }
 
-func displaysolarisLogo() {
-	yellow := color.New(color.FgYellow).SprintFunc()
-	fmt.Println(yellow(`  
-	                            +=                                                              
-                                   #+=                                                              
-                                  +#==                                                              
-                                 ==-*+=                                                             
-                                 ==:=*+=                                                            
-         +=                      ==---*+-                                                           
-         ==---                   +=-::===                                                           
-         ===+=====-:-   *++   **+*#===+--                                                           
-          ===-++**+===-=+*+  =****==+=-:++==--                                                      
-            =-=====-=+:=--*=--+=*++=:::*#=--                                                        
-              ===++-=---=====-=---::.:-==:::                                                        
-                =+==+---==--=--=+==--:==:.                                                          
-                =+=+=----***+==----=======                                                          
-                ++++-.:+##*=-:..:  
-           ==    =++.:=+===...    
-           +=+==:--:.=--+=::      ███████╗  ██████╗  ██╗       █████╗  ██████╗  ██╗ ███████╗
-            ===+=+====:===:       ██╔════╝ ██╔═══██╗ ██║      ██╔══██╗ ██╔══██╗ ██║ ██╔════╝
-        +*+=-:--==:-===+-:        ███████╗ ██║   ██║ ██║      ███████║ ██████╔╝ ██║ ███████╗
-      ==*+**++----===*++--        ╚════██║ ██║   ██║ ██║      ██╔══██║ ██╔══██╗ ██║ ╚════██║
-   **===-=++++-:*====+++-=        ███████║ ╚██████╔╝ ███████╗ ██║  ██║ ██║  ██║ ██║ ███████║
-=====-=***=-=+*++======+---       ╚══════╝  ╚═════╝  ╚══════╝ ╚═╝  ╚═╝ ╚═╝  ╚═╝ ╚═╝ ╚══════╝ 
-  ---===:.: ---=+--===-++--- 
-               :==::---=++====-==+=         
-                  =+==---=++++=--===--==     
-               =+===--:==:-=++++==------++*+=                                                       
-               =-=-:-+*+#=:::-------=*+++#+--                                                       
-                  +*##*+=:*=----=+=-=*#*=-=-:                                                       
-                 ==--=+++++-:..===-:=+-==-::                                                        
-                 +++++=-::...  =-+:-=*+=::.                                                         
-                 =-++:..       ==-:===+=:---                                                        
-                 ===+..             --*=-----                                                       
-                 ==+-.               :--+*---                                                       
-                ===-:                   -=+=-                                                       
-                                          ==:                                                       
-                                           =                                                        
-	`))
-	fmt.Println(yellow("\t\tWelcome to the Solaris Hangman Game!\n"))
+// addPoints adds points (or takes them off, if negative) to the session and
+// updates the team's score to match.
+func addPoints(team *Team, session *Session, points int) {
+	session.Score += points
+	teamsMu.Lock()
+	team.Score = combinedScore(session.previousScores, session.Score, session.scoring)
+	teamsMu.Unlock()
+	saveTeamToFirebase(teamCopy(team))
 }
 
-func displaygameoverLogo() {
-	red := color.New(color.FgHiRed).SprintFunc()
-	fmt.Println(red(`
+// rankTeams puts the teams in leaderboard order.
+func rankTeams(teams []Team, sessions []Session, settings GameSettings) []ranking.Standing {
+	byTeam := make(map[string][]ranking.Attempt)
+	for _, session := range sessions {
+		byTeam[session.Team] = append(byTeam[session.Team], rankedAttempt(session))
+	}
 
- ██████╗  █████╗ ███╗   ███╗███████╗     ██████╗ ██╗   ██╗███████╗██████╗ 
-██╔════╝ ██╔══██╗████╗ ████║██╔════╝    ██╔═══██╗██║   ██║██╔════╝██╔══██╗
-██║  ███╗███████║██╔████╔██║█████╗      ██║   ██║██║   ██║█████╗  ██████╔╝
-██║   ██║██╔══██║██║╚██╔╝██║██╔══╝      ██║   ██║╚██╗ ██╔╝██╔══╝  ██╔══██╗
-╚██████╔╝██║  ██║██║ ╚═╝ ██║███████╗    ╚██████╔╝ ╚████╔╝ ███████╗██║  ██║
- ╚═════╝ ╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝     ╚═════╝   ╚═══╝  ╚══════╝╚═╝  ╚═╝
-	`))
+	standings := make([]ranking.Standing, len(teams))
+	for i, team := range teams {
+		standings[i] = ranking.TeamStanding(team.Name, team.Score, byTeam[team.Name], settings.Scoring)
+	}
+	ranking.Rank(standings, settings.Tiebreakers)
+	return standings
 }
 
-func runRiddles(team *Team, riddlesSubset []Riddle, reader *bufio.Reader) {
-	green := color.New(color.FgGreen).SprintFunc()
-	blue := color.New(color.FgBlue).SprintFunc()
-	red := color.New(color.FgHiRed).SprintFunc()
-
-	wrongGuesses := 0
-	for i, riddle := range riddlesSubset {
-		select {
-		case <-timeUp:
-			fmt.Println(red("\nTime's up! The game is over."))
-			displaygameoverLogo()
-			return
-		default:
-		}
+// rankedAttempt is what the ranking needs of a session.
+func rankedAttempt(session Session) ranking.Attempt {
+	attempt := ranking.Attempt{
+		Number:       session.Attempt,
+		Score:        session.Score,
+		WrongGuesses: session.WrongGuesses,
+		Finished:     session.Status == statusFinished,
+		EndedAt:      session.EndedAt,
+	}
+	for _, question := range session.Results {
+		attempt.Seconds += question.Seconds
+		attempt.Hints += question.HintsUsed
+	}
+	return attempt
+}
 
-		if wrongGuesses >= len(hangmanStages)-1 {
-			fmt.Println(red("You've been hanged!"))
-			displaygameoverLogo()
-			break
-		}
+// getStandings ranks every team as things stand.
+func getStandings(settings GameSettings) ([]ranking.Standing, []Session, error) {
+	teams, err := getTeamsFromFirebase()
+	if err != nil {
+		return nil, nil, err
+	}
+	sessions, err := getSessionsFromFirebase()
+	if err != nil {
+		return nil, nil, err
+	}
+	return rankTeams(teams, sessions, settings), sessions, nil
+}
 
-		fmt.Printf("\n%s %s\n", green("Question "+fmt.Sprintf("%d:", i+1)), riddle.Question)
-		fmt.Print(green("Enter your guess: "))
-		guess, _ := reader.ReadString('\n')
-		guess = strings.TrimSpace(guess)
+// updateRanks re-ranks every team and stores the new rank on each finished
+// session whose team has moved, returning the ranks by team.
+func updateRanks(settings GameSettings) (map[string]int, error) {
+	standings, sessions, err := getStandings(settings)
+	if err != nil {
+		return nil, err
+	}
+	ranks := make(map[string]int)
+	for _, standing := range standings {
+		ranks[standing.Team] = standing.Rank
+	}
 
-		// Normalize both the guess and the correct answer
-		normalizedGuess := normalizeString(guess)
-		normalizedAnswer := normalizeString(riddle.Answer)
+	defer beginWrite()()
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
 
-		if normalizedGuess == normalizedAnswer {
-			fmt.Println(blue("Correct! You solved the riddle!"))
-			team.Score = team.Score + 5
-			saveTeamToFirebase(*team)
-		} else {
-			wrongGuesses++
-			fmt.Println(red("Incorrect guess!"))
-			fmt.Println(blue("The correct answer was: ", riddle.Answer))
-			drawHangman(wrongGuesses)
+	batch := client.Batch()
+	changed := false
+	for _, session := range sessions {
+		if session.Status != statusFinished || session.Rank == ranks[session.Team] {
+			continue
+		}
+		batch.Update(client.Collection("sessions").Doc(sessionID(session.Team, session.Attempt)), []firestore.Update{
+			{Path: "rank", Value: ranks[session.Team]},
+		})
+		changed = true
+	}
+	if changed {
+		if _, err := batch.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("error updating session ranks: %v", err)
 		}
-
-		fmt.Printf("Team %s Score: %d\n", team.Name, team.Score)
 	}
 
-	saveTeamToFirebase(*team)
+	return ranks, nil
 }
 
-// Helper function to normalize strings for comparison
-func normalizeString(s string) string {
-	// Remove all spaces and convert to lowercase
-	return strings.ToLower(strings.ReplaceAll(s, " ", ""))
-}
+func getSessionFromFirebase(teamName string, attempt int) (Session, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return Session{}, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
 
-func userInterface() {
-	reader := bufio.NewReader(os.Stdin)
-	green := color.New(color.FgGreen).SprintFunc()
-	red := color.New(color.FgHiRed).SprintFunc()
-	blue := color.New(color.FgBlue).SprintFunc()
-	yellow := color.New(color.FgYellow).SprintFunc()
+	doc, err := client.Collection("sessions").Doc(sessionID(teamName, attempt)).Get(ctx)
+	if err != nil {
+		return Session{}, fmt.Errorf("error retrieving session document: %v", err)
+	}
 
-	displaysolarisLogo()
+	var session Session
+	if err := doc.DataTo(&session); err != nil {
+		return Session{}, fmt.Errorf("error parsing session data: %v", err)
+	}
 
-	// Fetch approved teams from Firebase
-	approvedTeams, err := getApprovedTeamsFromFirebase()
+	return session, nil
+}
+
+func getSessionsFromFirebase() ([]Session, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
 	if err != nil {
-		log.Fatalf("Error fetching approved teams: %v\n", err)
+		return nil, fmt.Errorf("error creating Firestore client: %v", err)
 	}
+	defer client.Close()
 
-	var teamName string
-	var team *Team
-	teamEntered := false
-	passwordVerified := false
-	adminpasswordVerified := false
+	docs, err := client.Collection("sessions").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving sessions: %v", err)
+	}
 
-	for {
-		if !adminpasswordVerified {
-			fmt.Print(green("Enter admin the password to start the game: "))
-			passwordEntered, _ := reader.ReadString('\n')
-			passwordEntered = strings.TrimSpace(passwordEntered)
+	var sessions []Session
+	for _, doc := range docs {
+		var session Session
+		if err := doc.DataTo(&session); err != nil {
+			return nil, fmt.Errorf("error parsing session data: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
 
-			correctPassword, err := getPasswordFromFirebase()
-			if err != nil {
-				fmt.Printf("Error retrieving password: %v\n", err)
-				continue
-			}
+	return sessions, nil
+}
 
-			if passwordEntered != correctPassword {
-				fmt.Println(red("Incorrect password. Please try again."))
-				continue
-			}
+// heartbeatInterval is how often a game in play marks its session as
+// live. One left quiet for a few intervals was cut off without being saved.
+const heartbeatInterval = 10 * time.Second
 
-			adminpasswordVerified = true // Mark the password as verified
+var errPlayingElsewhere = errors.New("your team's game is still in play on another machine")
+
+// unfinishedSession returns the team's current attempt if it was left
+// mid-game and can still be resumed. One that is too old to resume is
+// closed off as abandoned, and one still being played elsewhere is an
+// error.
+func unfinishedSession(team *Team, settings GameSettings) (*Session, error) {
+	session, err := getSessionFromFirebase(team.Name, team.Attempts)
+	if err != nil || (session.Status != statusPlaying && session.Status != statusAborted) || session.Progress == nil {
+		return nil, nil
+	}
+	if session.Status == statusPlaying && time.Since(session.UpdatedAt) < 3*heartbeatInterval {
+		return nil, errPlayingElsewhere
+	}
+
+	if settings.ResumeEnabled && time.Since(session.UpdatedAt) <= settings.ResumeGrace {
+		return &session, nil
+	}
+
+	session.Status = statusFinished
+	session.EndReason = endAbandoned
+	session.Progress = nil
+	session.EndedAt = session.UpdatedAt
+	if err := saveSessionToFirebase(session); err != nil {
+		log.Printf("Error closing abandoned session: %v\n", err)
+	}
+	return nil, nil
+}
+
+func getApprovedTeamsFromFirebase() ([]string, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("approved_teams").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving approved teams: %v", err)
+	}
+
+	var approvedTeams []string
+	for _, doc := range docs {
+		approvedTeams = append(approvedTeams, doc.Ref.ID)
+	}
+
+	return approvedTeams, nil
+}
+
+// beginAttempt readies an existing team for its next game. A game cut
+// short by a crash is returned to pick up where it left off rather than
+// counting as a new attempt; otherwise the attempt is counted, unless the
+// team has used them all.
+func beginAttempt(team *Team, settings GameSettings) (*Session, error) {
+	resumable, err := unfinishedSession(team, settings)
+	if resumable != nil || err != nil {
+		return resumable, err
+	}
+	if settings.MaxAttempts > 0 && team.Attempts >= settings.MaxAttempts {
+		return nil, fmt.Errorf("your team has used all %d of its attempts", settings.MaxAttempts)
+	}
+	team.Attempts++
+	return nil, nil
+}
+
+// sentence turns an error into a sentence to show a player.
+func sentence(err error) string {
+	msg := err.Error()
+	return strings.ToUpper(msg[:1]) + msg[1:]
+}
+
+func validateTeam(approvedTeams []string, teamName string) bool {
+	for _, approvedTeam := range approvedTeams {
+		if approvedTeam == teamName {
+			return true
+		}
+	}
+	return false
+}
+
+func createPasswordForNewTeam(team *Team, term *terminal) {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	term.Print(green("This is your first login. Please create a password: "))
+	password := term.readLine()
+	password = strings.TrimSpace(password)
+	team.Password = password
+	saveTeamToFirebase(*team)
+}
+
+// maxPasswordTries is how many wrong passwords a connection may give
+// before it is turned away.
+const maxPasswordTries = 3
+
+func validatePassword(team *Team, term *terminal) bool {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	term.Print(green("Enter your password: "))
+	passwordEntered := term.readLine()
+	passwordEntered = strings.TrimSpace(passwordEntered)
+	return passwordEntered == team.Password
+}
+
+// gameClock counts down the time left in a game or round. The admin can
+// stop it and give it extra time while it runs.
+type gameClock struct {
+	mu       sync.Mutex
+	deadline time.Time
+	pausedAt time.Time // When it was stopped, or zero while it runs
+}
+
+func startTimer(duration time.Duration) *gameClock {
+	return &gameClock{deadline: time.Now().Add(duration)}
+}
+
+func (c *gameClock) expired() bool {
+	return c.remaining() <= 0
+}
+
+func (c *gameClock) remaining() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.pausedAt.IsZero() {
+		return max(c.deadline.Sub(c.pausedAt), 0)
+	}
+	return max(time.Until(c.deadline), 0)
+}
+
+// pause stops the clock until resume is called.
+func (c *gameClock) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pausedAt.IsZero() {
+		c.pausedAt = time.Now()
+	}
+}
+
+func (c *gameClock) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.pausedAt.IsZero() {
+		c.deadline = c.deadline.Add(time.Since(c.pausedAt))
+		c.pausedAt = time.Time{}
+	}
+}
+
+// extend gives the clock d more time.
+func (c *gameClock) extend(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = c.deadline.Add(d)
+}
+
+// formatDuration renders d the way the game shows times, e.g. "4min 30sec".
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	seconds := int(d.Seconds()) % 60
+	return fmt.Sprintf("%dmin %dsec", minutes, seconds)
+}
+
+func getGameDurationFromFirebase() (time.Duration, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	doc, err := client.Collection("game_settings").Doc("duration").Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error retrieving game duration: %v", err)
+	}
+
+	var data map[string]interface{}
+	doc.DataTo(&data)
+	minutes, ok := data["minutes"].(int64)
+	if !ok {
+		return 0, fmt.Errorf("invalid game duration format")
+	}
+
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// GameSettings are the admin-configurable game rules, read from the
+// game_settings collection when a game starts.
+type GameSettings struct {
+	IgnoreAccents bool // Treat "café" and "cafe" as the same answer
+	HintCost      int  // Points taken off for each hint revealed
+	SkipPenalty   int  // Points taken off for skipping a riddle
+
+	// Mix is how many riddles of each difficulty to serve; empty means
+	// riddlesPerGame riddles of any difficulty. RampUp orders the riddles
+	// from easy to hard.
+	Mix    map[string]int
+	RampUp bool
+
+	// Rounds, when set, split the game into themed rounds played in order,
+	// each with its own riddles and clock.
+	Rounds []Round
+
+	// Competition mode: a fixed Seed gives every team the same riddles,
+	// or RiddleIDs lists them outright. SameOrder also fixes their order.
+	Seed      int64
+	RiddleIDs []string
+	SameOrder bool
+
+	RepeatPolicy string // How to treat riddles the team has seen before
+	RevealPolicy string // When the answers to missed riddles are shown
+
+	MaxAttempts int    // Attempts allowed per team; 0 means no limit, 1 disallows re-attempts
+	Scoring     string // How attempt scores combine into the team's score
+
+	ResumeEnabled bool          // Whether an interrupted game can be picked up again
+	ResumeGrace   time.Duration // How long after the last checkpoint it can be
+
+	Tiebreakers []string // How teams on the same score are ordered
+
+	// Race mode: on the game server, teams are matched up RaceTeams at a
+	// time to race each other through the same RaceRiddles riddles, with
+	// RaceTime to solve each one.
+	Race        bool
+	RaceTeams   int
+	RaceRiddles int
+	RaceTime    time.Duration
+}
+
+// Round is one themed round of a game.
+type Round struct {
+	Name     string        // Shown to players in the round header
+	Category string        // Riddle category to draw from; empty means any
+	Riddles  int           // Number of riddles in the round
+	Duration time.Duration // Time allowed for the round
+}
+
+// intSetting reads a whole number from a settings document.
+func intSetting(data map[string]interface{}, key string, fallback int) int {
+	if value, ok := data[key].(int64); ok {
+		return int(value)
+	}
+	return fallback
+}
+
+// getGameSettingsFromFirebase returns the game settings, falling back to
+// the defaults for anything not set or if the settings can't be read.
+func getGameSettingsFromFirebase() (GameSettings, error) {
+	settings := GameSettings{HintCost: defaultHintCost, SkipPenalty: defaultSkipPenalty, RepeatPolicy: repeatExclude, RevealPolicy: revealImmediately, Scoring: scoreLatest,
+		ResumeEnabled: true, ResumeGrace: defaultResumeGrace, Tiebreakers: ranking.DefaultTiebreakers,
+		RaceTeams: defaultRaceTeams, RaceRiddles: defaultRaceRiddles, RaceTime: defaultRaceTime}
+
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return settings, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("game_settings").Documents(ctx).GetAll()
+	if err != nil {
+		return settings, fmt.Errorf("error retrieving game settings: %v", err)
+	}
+
+	for _, doc := range docs {
+		data := doc.Data()
+		switch doc.Ref.ID {
+		case "matching":
+			settings.IgnoreAccents, _ = data["ignoreAccents"].(bool)
+		case "hints":
+			settings.HintCost = intSetting(data, "cost", settings.HintCost)
+		case "skip":
+			settings.SkipPenalty = intSetting(data, "penalty", settings.SkipPenalty)
+		case "rounds":
+			rounds, _ := data["rounds"].([]interface{})
+			for _, r := range rounds {
+				roundData, ok := r.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				round := Round{
+					Riddles:  intSetting(roundData, "riddles", riddlesPerGame),
+					Duration: time.Duration(intSetting(roundData, "minutes", 5)) * time.Minute,
+				}
+				round.Name, _ = roundData["name"].(string)
+				round.Category, _ = roundData["category"].(string)
+				settings.Rounds = append(settings.Rounds, round)
+			}
+		case "race":
+			settings.Race, _ = data["enabled"].(bool)
+			settings.RaceTeams = max(intSetting(data, "teams", defaultRaceTeams), 2)
+			settings.RaceRiddles = intSetting(data, "riddles", defaultRaceRiddles)
+			settings.RaceTime = time.Duration(intSetting(data, "seconds", int(defaultRaceTime/time.Second))) * time.Second
+		case "ranking":
+			if tiebreakers, ok := data["tiebreakers"].([]interface{}); ok {
+				settings.Tiebreakers = nil
+				for _, tiebreaker := range tiebreakers {
+					if tiebreaker, ok := tiebreaker.(string); ok {
+						settings.Tiebreakers = append(settings.Tiebreakers, tiebreaker)
+					}
+				}
+			}
+		case "resume":
+			if enabled, ok := data["enabled"].(bool); ok {
+				settings.ResumeEnabled = enabled
+			}
+			settings.ResumeGrace = time.Duration(intSetting(data, "graceMinutes", int(defaultResumeGrace/time.Minute))) * time.Minute
+		case "attempts":
+			settings.MaxAttempts = intSetting(data, "maxAttempts", 0)
+			if scoring, ok := data["scoring"].(string); ok && scoring != "" {
+				settings.Scoring = scoring
+			}
+		case "reveal":
+			if policy, ok := data["policy"].(string); ok && policy != "" {
+				settings.RevealPolicy = policy
+			}
+		case "history":
+			if policy, ok := data["repeatPolicy"].(string); ok && policy != "" {
+				settings.RepeatPolicy = policy
+			}
+		case "competition":
+			settings.Seed, _ = data["seed"].(int64)
+			settings.SameOrder, _ = data["sameOrder"].(bool)
+			ids, _ := data["riddles"].([]interface{})
+			for _, id := range ids {
+				if id, ok := id.(string); ok {
+					settings.RiddleIDs = append(settings.RiddleIDs, id)
+				}
+			}
+		case "selection":
+			settings.RampUp, _ = data["rampUp"].(bool)
+			for _, difficulty := range difficulties {
+				if count := intSetting(data, difficulty, 0); count > 0 {
+					if settings.Mix == nil {
+						settings.Mix = make(map[string]int)
+					}
+					settings.Mix[difficulty] = count
+				}
+			}
+		}
+	}
+
+	return settings, nil
+}
+
+func getRiddlesFromFirebase() ([]Riddle, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	var riddlesFromFirebase []Riddle
+	docs, err := client.Collection("riddles").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving riddles: %v", err)
+	}
+
+	for _, doc := range docs {
+		var riddle Riddle
+		err := doc.DataTo(&riddle)
+		if err != nil {
+			return nil, fmt.Errorf("error converting document data to riddle: %v", err)
+		}
+		riddle.ID = doc.Ref.ID
+		riddlesFromFirebase = append(riddlesFromFirebase, riddle)
+	}
+
+	return riddlesFromFirebase, nil
+}
+
+// competition reports whether every team should get the same riddles.
+func (settings GameSettings) competition() bool {
+	return settings.Seed != 0 || len(settings.RiddleIDs) > 0
+}
+
+// riddlePool returns the riddles a game can draw from. Normally that is the
+// hardcoded and Firebase riddles shuffled by seed; an event's explicit
+// riddle list is returned as listed instead.
+func riddlePool(seed int64, riddleIDs []string) ([]Riddle, error) {
+	firebaseRiddles, err := getRiddlesFromFirebase() // Fetch riddles from Firebase
+	if err != nil {
+		return nil, err
+	}
+
+	if len(riddleIDs) > 0 {
+		var listed []Riddle
+		for _, id := range riddleIDs {
+			i := slices.IndexFunc(firebaseRiddles, func(r Riddle) bool { return r.ID == id })
+			if i < 0 {
+				log.Printf("Riddle %s from the event list no longer exists\n", id)
+				continue
+			}
+			listed = append(listed, firebaseRiddles[i])
+		}
+		return listed, nil
+	}
+
+	// Combine hardcoded riddles with Firebase riddles
+	allRiddles := append(riddles, firebaseRiddles...)
+
+	// Shuffle the combined list
+	shuffleRiddles(allRiddles, seed)
+
+	return allRiddles, nil
+}
+
+func shuffleRiddles(list []Riddle, seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(list), func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+}
+
+// selectRiddles picks num riddles from pool, or the difficulty mix from the
+// settings if there is one. An event's explicit riddle list is used whole.
+func selectRiddles(pool []Riddle, num int, settings GameSettings) []Riddle {
+	switch {
+	case len(settings.RiddleIDs) > 0:
+		return slices.Clone(pool)
+	case len(settings.Mix) > 0:
+		return pickMix(pool, settings.Mix)
+	default:
+		// Ensure we don't try to select more riddles than exist
+		num = min(num, len(pool))
+		return slices.Clone(pool[:num])
+	}
+}
+
+// arrangeRiddles puts selected riddles in the order they are asked: shuffled
+// by orderSeed if it is set, then easy to hard when rampUp is on.
+func arrangeRiddles(selected []Riddle, orderSeed int64, rampUp bool) {
+	if orderSeed != 0 {
+		shuffleRiddles(selected, orderSeed)
+	}
+	if rampUp {
+		sort.SliceStable(selected, func(i, j int) bool {
+			return difficultyRank(selected[i]) < difficultyRank(selected[j])
+		})
+	}
+}
+
+// wantedRiddles is how many riddles a game with these settings asks for.
+func wantedRiddles(settings GameSettings) int {
+	wanted := 0
+	switch {
+	case len(settings.Rounds) > 0:
+		for _, round := range settings.Rounds {
+			wanted += round.Riddles
+		}
+	case len(settings.Mix) > 0:
+		for _, count := range settings.Mix {
+			wanted += count
+		}
+	default:
+		wanted = riddlesPerGame
+	}
+	return wanted
+}
+
+// excludeSeen applies the repeat policy to pool, dropping the riddles in
+// seen or moving them to the back. It returns the new pool and how many
+// unseen riddles it has.
+func excludeSeen(pool []Riddle, seen []string, policy string) ([]Riddle, int) {
+	if policy == repeatAllow {
+		return pool, len(pool)
+	}
+
+	var unseen, repeats []Riddle
+	for _, riddle := range pool {
+		if slices.Contains(seen, riddleKey(riddle)) {
+			repeats = append(repeats, riddle)
+		} else {
+			unseen = append(unseen, riddle)
+		}
+	}
+
+	if policy == repeatFill {
+		return append(unseen, repeats...), len(unseen)
+	}
+	return unseen, len(unseen)
+}
+
+// roundRiddles picks the riddles for each round from pool, drawing on the
+// round's category and never repeating a riddle between rounds.
+func roundRiddles(pool []Riddle, settings GameSettings) [][]Riddle {
+	used := make(map[string]bool)
+	var rounds [][]Riddle
+	for _, round := range settings.Rounds {
+		var candidates []Riddle
+		for _, riddle := range pool {
+			if used[riddle.Question] {
+				continue
+			}
+			if round.Category == "" || strings.EqualFold(riddle.Category, round.Category) {
+				candidates = append(candidates, riddle)
+			}
+		}
+
+		selected := selectRiddles(candidates, round.Riddles, GameSettings{})
+		if len(selected) < round.Riddles {
+			log.Printf("Only %d riddles available for round %q, wanted %d\n", len(selected), round.Name, round.Riddles)
+		}
+		for _, riddle := range selected {
+			used[riddle.Question] = true
+		}
+		rounds = append(rounds, selected)
+	}
+	return rounds
+}
+
+// pickMix takes the requested number of riddles of each difficulty from
+// pool, warning when a difficulty runs short. The riddles stay in pool
+// order rather than being grouped by difficulty, so the shuffled pool
+// keeps them mixed unless RampUp sorts them later.
+func pickMix(pool []Riddle, mix map[string]int) []Riddle {
+	var selected []Riddle
+	got := make(map[string]int)
+	for _, riddle := range pool {
+		difficulty := riddleDifficulty(riddle)
+		if got[difficulty] < mix[difficulty] {
+			selected = append(selected, riddle)
+			got[difficulty]++
+		}
+	}
+	for _, difficulty := range difficulties {
+		if want := mix[difficulty]; got[difficulty] < want {
+			log.Printf("Only %d %s riddles available, wanted %d\n", got[difficulty], difficulty, want)
+		}
+	}
+	return selected
+}
+
+// difficultyRank orders riddles from easiest to hardest.
+func difficultyRank(riddle Riddle) int {
+	return slices.Index(difficulties, riddleDifficulty(riddle))
+}
+
+func drawHangman(stage int, term *terminal) {
+	term.Println(hangmanStages[stage])
+}
+
+// startScoreUpdater saves the team every second until the returned
+// function is called.
+func startScoreUpdater(team *Team) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(1 * time.Second) // Update score every 1 second
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				saveTeamToFirebase(teamCopy(team))
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// activeGame is a game in play, tracked so it can be saved on shutdown.
+type activeGame struct {
+	stopUpdater func()
+	notify      func(text string) // Tells the team something mid-game
+	racing      bool              // Raced against other teams, on the race's clock instead of the game's
+}
+
+var (
+	activeGamesMu sync.Mutex
+	activeGames   = map[*game]activeGame{}
+	claimedTeams  = map[string]bool{} // Teams logged in somewhere, whether or not their game has started
+)
+
+func addActiveGame(g *game, active activeGame) {
+	controlsOnce.Do(func() { go pollControls(time.Second) })
+	activeGamesMu.Lock()
+	defer activeGamesMu.Unlock()
+	activeGames[g] = active
+}
+
+func removeActiveGame(g *game) {
+	g.stopHeartbeat()
+	activeGamesMu.Lock()
+	defer activeGamesMu.Unlock()
+	delete(activeGames, g)
+}
+
+// claimTeam keeps the team to one terminal or browser from login until it
+// leaves. It reports false if the team is already logged in somewhere;
+// otherwise the returned function gives the team up again.
+func claimTeam(teamName string) (func(), bool) {
+	activeGamesMu.Lock()
+	defer activeGamesMu.Unlock()
+	if claimedTeams[teamName] {
+		return nil, false
+	}
+	claimedTeams[teamName] = true
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			activeGamesMu.Lock()
+			defer activeGamesMu.Unlock()
+			delete(claimedTeams, teamName)
+		})
+	}, true
+}
+
+// Announcement is a message from the admin to everyone playing.
+type Announcement struct {
+	Text   string    `json:"text" firestore:"text"`
+	SentAt time.Time `json:"sentAt" firestore:"sentAt"`
+}
+
+// getAnnouncementsFromFirebase returns the announcements sent after since,
+// oldest first.
+func getAnnouncementsFromFirebase(since time.Time) ([]Announcement, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	docs, err := client.Collection("announcements").Where("sentAt", ">", since).OrderBy("sentAt", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving announcements: %v", err)
+	}
+
+	var announcements []Announcement
+	for _, doc := range docs {
+		var announcement Announcement
+		if err := doc.DataTo(&announcement); err != nil {
+			return nil, fmt.Errorf("error converting document data to announcement: %v", err)
+		}
+		announcements = append(announcements, announcement)
+	}
+	return announcements, nil
+}
+
+// listeners are everyone to pass the admin's announcements on to: every
+// connected player and the leaderboard.
+var listeners struct {
+	sync.Mutex
+	next int
+	tell map[int]func(Announcement)
+}
+
+// listen passes the admin's announcements to tell until the returned
+// function is called.
+func listen(tell func(Announcement)) func() {
+	listeners.Lock()
+	defer listeners.Unlock()
+	if listeners.tell == nil {
+		listeners.tell = make(map[int]func(Announcement))
+	}
+	id := listeners.next
+	listeners.next++
+	listeners.tell[id] = tell
+	return func() {
+		listeners.Lock()
+		defer listeners.Unlock()
+		delete(listeners.tell, id)
+	}
+}
+
+// pollAnnouncements passes on the announcements sent since the game
+// started, checking for new ones every interval.
+func pollAnnouncements(interval time.Duration) {
+	since := time.Now()
+	for range time.Tick(interval) {
+		announcements, err := getAnnouncementsFromFirebase(since)
+		if err != nil {
+			log.Printf("Error getting announcements: %v\n", err)
+			continue
+		}
+		for _, announcement := range announcements {
+			listeners.Lock()
+			tell := slices.Collect(maps.Values(listeners.tell))
+			listeners.Unlock()
+			for _, tell := range tell {
+				tell(announcement)
+			}
+			since = announcement.SentAt
+		}
+	}
+}
+
+// Control is the admin's hold over games in play, for everyone in
+// game_settings/control or for one team in team_controls.
+type Control struct {
+	Paused       bool `firestore:"paused"`
+	ExtraSeconds int  `firestore:"extraSeconds"` // All the extra time granted so far
+}
+
+// getControlsFromFirebase returns the controls for everyone and for each
+// team that has any.
+func getControlsFromFirebase() (Control, map[string]Control, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return Control{}, nil, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	var all Control
+	doc, err := client.Collection("game_settings").Doc("control").Get(ctx)
+	if err != nil && (doc == nil || doc.Exists()) {
+		return Control{}, nil, fmt.Errorf("error retrieving controls: %v", err)
+	}
+	if doc.Exists() {
+		if err := doc.DataTo(&all); err != nil {
+			return Control{}, nil, fmt.Errorf("error converting document data to controls: %v", err)
+		}
+	}
+
+	docs, err := client.Collection("team_controls").Documents(ctx).GetAll()
+	if err != nil {
+		return Control{}, nil, fmt.Errorf("error retrieving team controls: %v", err)
+	}
+	teams := make(map[string]Control)
+	for _, doc := range docs {
+		var control Control
+		if err := doc.DataTo(&control); err != nil {
+			return Control{}, nil, fmt.Errorf("error converting document data to controls: %v", err)
+		}
+		teams[doc.Ref.ID] = control
+	}
+
+	return all, teams, nil
+}
+
+var controlsOnce sync.Once
+
+// pollControls applies the admin's controls to every game in play each
+// interval. A paused event pauses every game too.
+func pollControls(interval time.Duration) {
+	for range time.Tick(interval) {
+		all, teams, err := getControlsFromFirebase()
+		if err != nil {
+			log.Printf("Error getting controls: %v\n", err)
+			continue
+		}
+		event, _ := currentEvent()
+
+		// Telling the teams writes to their connections, so it is done
+		// outside the lock to keep a stalled client from holding up logins
+		activeGamesMu.Lock()
+		games := maps.Clone(activeGames)
+		activeGamesMu.Unlock()
+
+		for g, active := range games {
+			// A race keeps every team on one clock, so one team can't be
+			// paused or given more time than the rest
+			if active.racing {
+				continue
+			}
+			team := teams[g.team.Name]
+			paused := all.Paused || team.Paused || event.stage() == eventPaused
+			for _, text := range g.control(paused, all.ExtraSeconds+team.ExtraSeconds) {
+				active.notify(text)
+			}
+		}
+	}
+}
+
+// dropActiveGame saves g as aborted for reason if it is still in play, for
+// when the player goes away mid-game.
+func dropActiveGame(g *game, reason string) {
+	activeGamesMu.Lock()
+	active, ok := activeGames[g]
+	if !ok {
+		activeGamesMu.Unlock()
+		return
+	}
+	// The save is counted as in flight before the game leaves the map, so
+	// a shutdown either saves the game itself or waits for this save. It
+	// runs outside the lock so logins and the admin's controls don't wait.
+	defer beginWrite()()
+	delete(activeGames, g)
+	activeGamesMu.Unlock()
+	active.stopUpdater()
+
+	abortGame(g, reason)
+}
+
+// handleShutdown exits cleanly on SIGINT or SIGTERM. Saves already in
+// flight are let finish, then every game in play is saved as aborted with
+// the time it had left, so it can be resumed.
+func handleShutdown() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		reason := endTerminated
+		if sig == os.Interrupt {
+			reason = endInterrupted
+		}
+		shutdown(reason)
+	}()
+}
+
+// shutdown saves every game in play as aborted for reason and exits.
+func shutdown(reason string) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	// Telling the teams writes to their connections, so it is done outside
+	// the lock to keep a stalled client from holding up the exit
+	activeGamesMu.Lock()
+	games := maps.Clone(activeGames)
+	activeGamesMu.Unlock()
+	for _, active := range games {
+		active.stopUpdater()
+		active.notify("Saving your game before exiting...")
+	}
+
+	// Taking the write lock waits for any save in flight and blocks the
+	// rest, so nothing lands after the final save.
+	writeMu.Lock()
+	for g := range games {
+		abortGame(g, reason)
+	}
+	fmt.Println(yellow("Exiting..."))
+	os.Exit(0)
+}
+
+// abortGame records a game in play as cut short, keeping its progress and
+// remaining time. The caller must hold writeMu, for reading or writing.
+func abortGame(g *game, reason string) {
+	g.stopHeartbeat()
+	if clock := g.timer(); g.session.Progress != nil && clock != nil {
+		g.session.Progress.Remaining = clock.remaining().Seconds()
+	}
+	g.session.Status = statusAborted
+	g.session.EndReason = reason
+	g.session.UpdatedAt = time.Now()
+
+	writeTeamToFirebase(teamCopy(g.team))
+	if err := writeSessionToFirebase(*g.session); err != nil {
+		log.Printf("Error saving aborted session: %v\n", err)
+	}
+	writeSessionEventToFirebase(g.id, g.stamp(SessionEvent{Type: playEnded, Text: reason}))
+}
+
+func displaysolarisLogo(term *terminal) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	term.Println(yellow(`  
+	                            +=                                                              
+                                   #+=                                                              
+                                  +#==                                                              
+                                 ==-*+=                                                             
+                                 ==:=*+=                                                            
+         +=                      ==---*+-                                                           
+         ==---                   +=-::===                                                           
+         ===+=====-:-   *++   **+*#===+--                                                           
+          ===-++**+===-=+*+  =****==+=-:++==--                                                      
+            =-=====-=+:=--*=--+=*++=:::*#=--                                                        
+              ===++-=---=====-=---::.:-==:::                                                        
+                =+==+---==--=--=+==--:==:.                                                          
+                =+=+=----***+==----=======                                                          
+                ++++-.:+##*=-:..:  
+           ==    =++.:=+===...    
+           +=+==:--:.=--+=::      ███████╗  ██████╗  ██╗       █████╗  ██████╗  ██╗ ███████╗
+            ===+=+====:===:       ██╔════╝ ██╔═══██╗ ██║      ██╔══██╗ ██╔══██╗ ██║ ██╔════╝
+        +*+=-:--==:-===+-:        ███████╗ ██║   ██║ ██║      ███████║ ██████╔╝ ██║ ███████╗
+      ==*+**++----===*++--        ╚════██║ ██║   ██║ ██║      ██╔══██║ ██╔══██╗ ██║ ╚════██║
+   **===-=++++-:*====+++-=        ███████║ ╚██████╔╝ ███████╗ ██║  ██║ ██║  ██║ ██║ ███████║
+=====-=***=-=+*++======+---       ╚══════╝  ╚═════╝  ╚══════╝ ╚═╝  ╚═╝ ╚═╝  ╚═╝ ╚═╝ ╚══════╝ 
+  ---===:.: ---=+--===-++--- 
+               :==::---=++====-==+=         
+                  =+==---=++++=--===--==     
+               =+===--:==:-=++++==------++*+=                                                       
+               =-=-:-+*+#=:::-------=*+++#+--                                                       
+                  +*##*+=:*=----=+=-=*#*=-=-:                                                       
+                 ==--=+++++-:..===-:=+-==-::                                                        
+                 +++++=-::...  =-+:-=*+=::.                                                         
+                 =-++:..       ==-:===+=:---                                                        
+                 ===+..             --*=-----                                                       
+                 ==+-.               :--+*---                                                       
+                ===-:                   -=+=-                                                       
+                                          ==:                                                       
+                                           =                                                        
+	`))
+	term.Println(yellow("\t\tWelcome to the Solaris Hangman Game!\n"))
+}
+
+func displaygameoverLogo(term *terminal) {
+	red := color.New(color.FgHiRed).SprintFunc()
+	term.Println(red(`
+
+ ██████╗  █████╗ ███╗   ███╗███████╗     ██████╗ ██╗   ██╗███████╗██████╗ 
+██╔════╝ ██╔══██╗████╗ ████║██╔════╝    ██╔═══██╗██║   ██║██╔════╝██╔══██╗
+██║  ███╗███████║██╔████╔██║█████╗      ██║   ██║██║   ██║█████╗  ██████╔╝
+██║   ██║██╔══██║██║╚██╔╝██║██╔══╝      ██║   ██║╚██╗ ██╔╝██╔══╝  ██╔══██╗
+╚██████╔╝██║  ██║██║ ╚═╝ ██║███████╗    ╚██████╔╝ ╚████╔╝ ███████╗██║  ██║
+ ╚═════╝ ╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝     ╚═════╝   ╚═══╝  ╚══════╝╚═╝  ╚═╝
+	`))
+}
+
+const gameHelp = `Commands:
+  hint   reveal the next hint for this riddle, if it has any
+  skip   give up on this riddle
+  pass   come back to this riddle after the others
+  quit   end the game now
+  help   show this list`
+
+// Why a move in the game was refused.
+var (
+	errTimeUp     = errors.New("time's up")
+	errNoQuestion = errors.New("no riddle is being asked")
+	errNoHints    = errors.New("there are no hints for this riddle")
+	errHintsUsed  = errors.New("you've already seen every hint for this riddle")
+	errPassedOnce = errors.New("this riddle was already passed once")
+	errPaused     = errors.New("the game is paused by the admin")
+)
+
+// game is a team's game in play. It holds the rules of the quiz; the
+// terminal and the web API both drive it and only differ in how they show
+// it.
+type game struct {
+	team     *Team
+	session  *Session
+	settings GameSettings
+	id       string // The session's document ID
+
+	asking int       // Index of the riddle being asked, or -1
+	asked  time.Time // When it was first asked
+
+	// The admin's controls can reach the game while it plays, so the
+	// clock is only touched under mu.
+	mu      sync.Mutex
+	clock   *gameClock    // Timer for the round in play, nil between rounds
+	held    bool          // Paused by the admin
+	resumed chan struct{} // Closed when the admin resumes the game
+	granted int           // Extra seconds from the admin already given, or -1 before the controls are first seen
+
+	// The team's score and wrong guesses as the game last recorded them,
+	// for the admin's controls to report without touching the session
+	score        int
+	wrongGuesses int
+
+	stopBeating func() // Ends the heartbeat, once the game has one
+}
+
+func newGame(team *Team, session *Session, settings GameSettings) *game {
+	return &game{
+		team: team, session: session, settings: settings, id: sessionID(session.Team, session.Attempt),
+		asking: -1, granted: -1, score: session.Score, wrongGuesses: session.WrongGuesses,
+	}
+}
+
+// start plans the riddles for a new game, or picks a resumed one up where
+// it left off. It returns a notice for the team when the game will be short
+// of new riddles.
+func (g *game) start(gameDuration time.Duration) (string, error) {
+	g.begin()
+	if g.session.Progress != nil {
+		g.heartbeat()
+		return "", nil
+	}
+	progress, notice, err := planGame(g.team, g.session, g.settings, gameDuration)
+	if err != nil {
+		return "", err
+	}
+	g.session.Progress = progress
+	g.heartbeat()
+	return notice, nil
+}
+
+// heartbeat marks the session as live every heartbeatInterval until
+// stopHeartbeat is called, so another machine the team logs in on doesn't
+// take the game for one left mid-game.
+func (g *game) heartbeat() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := touchSessionInFirebase(g.id); err != nil {
+					log.Printf("Error marking session live: %v\n", err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stopBeating = func() { once.Do(func() { close(done) }) }
+}
+
+// stopHeartbeat stops marking the session as live, once the game is over
+// or put away.
+func (g *game) stopHeartbeat() {
+	g.mu.Lock()
+	stop := g.stopBeating
+	g.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// begin puts the session in play. The team's standing while it plays is
+// its earlier attempts combined with this one under the scoring policy.
+func (g *game) begin() {
+	previousScores, err := getSessionScoresFromFirebase(g.team.Name, g.session.Attempt)
+	if err != nil {
+		log.Printf("Error fetching earlier attempts: %v\n", err)
+	}
+	g.session.previousScores = previousScores
+	g.session.scoring = g.settings.Scoring
+	addPoints(g.team, g.session, 0)
+
+	g.session.Status = statusPlaying
+	g.session.EndReason = endCompleted
+}
+
+// beginRound starts the clock on the current round with whatever time it
+// has left.
+func (g *game) beginRound() {
+	g.setClock(startTimer(time.Duration(g.session.Progress.Remaining * float64(time.Second))))
+}
+
+// setClock replaces the round's clock, stopped if the admin has paused
+// the game.
+func (g *game) setClock(clock *gameClock) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if clock != nil && g.held {
+		clock.pause()
+	}
+	g.clock = clock
+}
+
+// timer is the clock of the round in play, nil between rounds.
+func (g *game) timer() *gameClock {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.clock
+}
+
+// control applies the admin's controls to the game, pausing or resuming
+// it and adding any time granted since they were last applied. It returns
+// what the team should be told.
+func (g *game) control(paused bool, granted int) []string {
+	var events []SessionEvent
+	defer func() {
+		for _, event := range events {
+			saveSessionEventToFirebase(g.id, g.standing(event))
+		}
+	}()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Time granted between rounds waits for the next round's clock
+	var news []string
+	switch {
+	case g.granted < 0 || granted < g.granted:
+		g.granted = granted
+	case granted > g.granted && g.clock != nil:
+		extra := time.Duration(granted-g.granted) * time.Second
+		g.clock.extend(extra)
+		g.granted = granted
+		news = append(news, fmt.Sprintf("The admin has given you %s more.", formatDuration(extra)))
+		events = append(events, SessionEvent{Type: playExtended, Text: formatDuration(extra)})
+	}
+
+	if paused != g.held {
+		g.held = paused
+		if paused {
+			g.resumed = make(chan struct{})
+			if g.clock != nil {
+				g.clock.pause()
+			}
+			news = append(news, "The game has been paused by the admin. Your clock is stopped.")
+			events = append(events, SessionEvent{Type: playPaused})
+		} else {
+			close(g.resumed)
+			events = append(events, SessionEvent{Type: playResumed})
+			if g.clock != nil {
+				g.clock.resume()
+				news = append(news, fmt.Sprintf("The game has resumed. %s left.", formatDuration(g.clock.remaining())))
+			} else {
+				news = append(news, "The game has resumed.")
+			}
+		}
+	}
+	return news
+}
+
+// stamp fills in the team's standing on event from the session. Only
+// whoever plays the game may call it.
+func (g *game) stamp(event SessionEvent) SessionEvent {
+	g.mu.Lock()
+	g.score, g.wrongGuesses = g.session.Score, g.session.WrongGuesses
+	g.mu.Unlock()
+	return g.standing(event)
+}
+
+// standing fills in the team's standing on event as it was last stamped,
+// which is safe from any goroutine.
+func (g *game) standing(event SessionEvent) SessionEvent {
+	g.mu.Lock()
+	event.Score = g.score
+	event.LivesLeft = len(hangmanStages) - 1 - g.wrongGuesses
+	g.mu.Unlock()
+	event.Remaining = g.remaining().Seconds()
+	event.Paused = g.paused()
+	event.At = time.Now()
+	return event
+}
+
+// publish records event for anyone watching the game.
+func (g *game) publish(event SessionEvent) {
+	saveSessionEventToFirebase(g.id, g.stamp(event))
+}
+
+// paused reports whether the admin has paused the game.
+func (g *game) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.held
+}
+
+// waitWhilePaused returns once the admin has resumed the game, if it is
+// paused.
+func (g *game) waitWhilePaused() {
+	g.mu.Lock()
+	held, resumed := g.held, g.resumed
+	g.mu.Unlock()
+	if held {
+		<-resumed
+	}
+}
+
+// remaining is the time left in the round in play.
+func (g *game) remaining() time.Duration {
+	clock := g.timer()
+	if clock == nil {
+		return 0
+	}
+	return clock.remaining()
+}
+
+// question returns the index of the riddle to ask, moving on to the ones
+// that were passed once the rest are done. When the round is over it
+// returns -1 and the reason.
+func (g *game) question() (int, string) {
+	progress := g.session.Progress
+	if g.timer().expired() {
+		return -1, endTimedOut
+	}
+	if g.asking >= 0 {
+		return g.asking, ""
+	}
+	if len(progress.Queue) == 0 {
+		if progress.FinalPass || len(progress.Deferred) == 0 {
+			return -1, endCompleted
+		}
+		progress.Queue, progress.Deferred, progress.FinalPass = progress.Deferred, nil, true
+	}
+
+	i := progress.Queue[0]
+	riddle := progress.Riddles[i]
+	result := &progress.Results[i]
+	result.Question = riddle.Question
+	result.Answer = riddle.Answer
+
+	g.asking, g.asked = i, time.Now()
+	teamsMu.Lock()
+	if !slices.Contains(g.team.SeenRiddles, riddleKey(riddle)) {
+		g.team.SeenRiddles = append(g.team.SeenRiddles, riddleKey(riddle))
+	}
+	teamsMu.Unlock()
+	g.checkpoint()
+	g.publish(SessionEvent{Type: playAsked, Riddle: i + 1, Question: riddle.Question})
+	return i, ""
+}
+
+// current returns the riddle being asked and its result so far. A move on
+// it made after the clock ran out doesn't count.
+func (g *game) current() (Riddle, *QuestionResult, error) {
+	if g.asking < 0 {
+		return Riddle{}, nil, errNoQuestion
+	}
+	if g.timer().expired() {
+		return Riddle{}, nil, errTimeUp
+	}
+	return g.session.Progress.Riddles[g.asking], &g.session.Progress.Results[g.asking], nil
+}
+
+// moveOn takes the riddle being asked off the queue.
+func (g *game) moveOn(result *QuestionResult) {
+	result.Seconds += time.Since(g.asked).Seconds()
+	g.session.Progress.Queue = g.session.Progress.Queue[1:]
+	g.asking = -1
+}
+
+// answer checks a guess at the riddle being asked and reports whether it
+// was right.
+func (g *game) answer(guess string) (bool, error) {
+	riddle, result, err := g.current()
+	if err != nil {
+		return false, err
+	}
+	i := g.asking
+	g.moveOn(result)
+
+	g.session.Answered++
+	result.Guess = guess
+	matched, correct := matchRiddle(guess, riddle, g.settings)
+	if correct {
+		result.Correct = true
+		result.Matched = matched
+		result.Points += riddlePoints(riddle)
+		addPoints(g.team, g.session, riddlePoints(riddle))
+	} else {
+		g.session.WrongGuesses++
+	}
+	g.session.Results = append(g.session.Results, *result)
+	g.publish(SessionEvent{Type: playAnswered, Riddle: i + 1, Question: riddle.Question, Guess: guess, Correct: correct})
+
+	if !g.hanged() {
+		g.checkpoint()
+	}
+	return correct, nil
+}
+
+// hanged reports whether the team has reached the last stage of the
+// gallows, which ends the game even on the final riddle.
+func (g *game) hanged() bool {
+	return g.session.WrongGuesses >= len(hangmanStages)-1
+}
+
+// hint reveals the next hint for the riddle being asked and charges the
+// team for it.
+func (g *game) hint() (string, error) {
+	riddle, result, err := g.current()
+	if err != nil {
+		return "", err
+	}
+	if len(riddle.Hints) == 0 {
+		return "", errNoHints
+	}
+	if result.HintsUsed >= len(riddle.Hints) {
+		return "", errHintsUsed
+	}
+
+	hint := riddle.Hints[result.HintsUsed]
+	result.HintsUsed++
+	result.Points -= g.settings.HintCost
+	addPoints(g.team, g.session, -g.settings.HintCost)
+	g.checkpoint()
+	g.publish(SessionEvent{Type: playHint, Riddle: g.asking + 1, Question: riddle.Question, Text: hint})
+	return hint, nil
+}
+
+// pass puts the riddle being asked off until the others are done. Each
+// riddle can only be passed once.
+func (g *game) pass() error {
+	riddle, result, err := g.current()
+	if err != nil {
+		return err
+	}
+	if g.session.Progress.FinalPass {
+		return errPassedOnce
+	}
+
+	i := g.asking
+	g.moveOn(result)
+	result.Passed = true
+	g.session.Progress.Deferred = append(g.session.Progress.Deferred, i)
+	g.checkpoint()
+	g.publish(SessionEvent{Type: playPassed, Riddle: i + 1, Question: riddle.Question})
+	return nil
+}
+
+// skip gives up on the riddle being asked, at the cost of the skip penalty.
+func (g *game) skip() error {
+	riddle, result, err := g.current()
+	if err != nil {
+		return err
+	}
+
+	i := g.asking
+	g.moveOn(result)
+	result.Skipped = true
+	result.Points -= g.settings.SkipPenalty
+	addPoints(g.team, g.session, -g.settings.SkipPenalty)
+	g.session.Results = append(g.session.Results, *result)
+	g.checkpoint()
+	g.publish(SessionEvent{Type: playSkipped, Riddle: i + 1, Question: riddle.Question})
+	return nil
+}
+
+// endRound closes the round in play for reason and reports whether
+// another round follows. Being hanged or quitting ends the whole game;
+// running out of time only ends a themed round.
+func (g *game) endRound(reason string) bool {
+	progress := g.session.Progress
+	g.setClock(nil)
+
+	// The riddle left mid-question and those passed and never got back to
+	// still count, hints and all, as left unanswered
+	pending := progress.Deferred
+	if progress.FinalPass {
+		pending = progress.Queue
+	} else if g.asking >= 0 {
+		pending = append([]int{g.asking}, pending...)
+	}
+	for _, i := range pending {
+		result := &progress.Results[i]
+		if i == g.asking {
+			result.Seconds += time.Since(g.asked).Seconds()
+		}
+		result.Skipped = true
+		g.session.Results = append(g.session.Results, *result)
+	}
+	g.asking = -1
+
+	if !progress.Themed {
+		g.session.EndReason = reason
+		return false
+	}
+
+	plan := progress.Rounds[progress.Round]
+	g.session.Rounds = append(g.session.Rounds, RoundResult{
+		Name:      plan.Name,
+		EndReason: reason,
+		Score:     g.session.Score - progress.RoundStartScore,
+		Answered:  g.session.Answered - progress.RoundStartAnswered,
+		Total:     plan.Riddles,
+	})
+
+	if reason == endHanged || reason == endQuit {
+		g.session.EndReason = reason
+		return false
+	}
+	if progress.Round+1 == len(progress.Rounds) {
+		return false
+	}
+	progress.startRound(progress.Round+1, g.session)
+	saveProgress(g.session)
+	g.publish(SessionEvent{Type: playRound, Text: progress.Rounds[progress.Round].Name})
+	return true
+}
+
+// finish stamps the session with the final score and lives and saves it
+// alongside the team, then re-ranks the teams to give it its rank.
+func (g *game) finish() {
+	g.stopHeartbeat()
+	session := g.session
+	session.Status = statusFinished
+	session.Progress = nil
+	session.LivesLeft = len(hangmanStages) - 1 - session.WrongGuesses
+	session.EndedAt = time.Now()
+	session.UpdatedAt = session.EndedAt
+
+	saveTeamToFirebase(teamCopy(g.team))
+	if err := saveSessionToFirebase(*session); err != nil {
+		log.Printf("Error saving session: %v\n", err)
+		return
+	}
+	g.publish(SessionEvent{Type: playEnded, Text: session.EndReason})
+	ranks, err := updateRanks(g.settings)
+	if err != nil {
+		log.Printf("Error ranking teams: %v\n", err)
+		return
+	}
+	session.Rank = ranks[g.team.Name]
+}
+
+// checkpoint saves how far the game has got, so it can be resumed with the
+// time that was left if the terminal dies.
+func (g *game) checkpoint() {
+	g.session.Progress.Remaining = g.remaining().Seconds()
+	saveProgress(g.session)
+}
+
+func saveProgress(session *Session) {
+	session.UpdatedAt = time.Now()
+	if err := saveSessionToFirebase(*session); err != nil {
+		log.Printf("Error saving game progress: %v\n", err)
+	}
+}
+
+// planGame picks the riddles for a new game, either one timed set or a
+// sequence of themed rounds, and lays them out as the session's progress.
+// It also returns a notice for the team when they are short of new riddles.
+func planGame(team *Team, session *Session, settings GameSettings, gameDuration time.Duration) (*Progress, string, error) {
+	// In competition mode the seed is fixed for the event so every team is
+	// served the same riddles; otherwise each game gets its own.
+	session.Seed = settings.Seed
+	if !settings.competition() {
+		session.Seed = time.Now().UnixNano()
+	} else if !settings.SameOrder {
+		session.OrderSeed = time.Now().UnixNano()
+	}
+
+	// Fetch riddles from Firebase or hardcoded ones
+	pool, err := riddlePool(session.Seed, settings.RiddleIDs)
+	if err != nil {
+		return nil, "", fmt.Errorf("error fetching riddles: %v", err)
+	}
+
+	// Keep re-attempts from being served riddles whose answers they've
+	// already seen. Competition mode is left alone so every team still gets
+	// the same set.
+	var notice string
+	if !settings.competition() {
+		var unseen int
+		pool, unseen = excludeSeen(pool, team.SeenRiddles, settings.RepeatPolicy)
+		if wanted := wantedRiddles(settings); unseen < wanted && settings.RepeatPolicy != repeatAllow {
+			if settings.RepeatPolicy == repeatFill {
+				notice = fmt.Sprintf("Your team has only %d new riddles left; the rest of this game repeats riddles you've seen.", unseen)
+			} else {
+				notice = fmt.Sprintf("Your team has only %d new riddles left, so this game will be shorter than %d riddles.", unseen, wanted)
+			}
+		}
+	}
+
+	progress := &Progress{}
+	if len(settings.Rounds) == 0 {
+		riddlesSubset := selectRiddles(pool, riddlesPerGame, settings)
+		arrangeRiddles(riddlesSubset, session.OrderSeed, settings.RampUp)
+		progress.Riddles = riddlesSubset
+		progress.Rounds = []RoundPlan{{Riddles: len(riddlesSubset), Seconds: gameDuration.Seconds()}}
+	} else {
+		progress.Themed = true
+		for i, riddlesSubset := range roundRiddles(pool, settings) {
+			arrangeRiddles(riddlesSubset, session.OrderSeed, settings.RampUp)
+			round := settings.Rounds[i]
+			name := round.Name
+			if name == "" {
+				name = fmt.Sprintf("Round %d", i+1)
+			}
+			progress.Riddles = append(progress.Riddles, riddlesSubset...)
+			progress.Rounds = append(progress.Rounds, RoundPlan{Name: name, Riddles: len(riddlesSubset), Seconds: round.Duration.Seconds()})
+		}
+	}
+	progress.Results = make([]QuestionResult, len(progress.Riddles))
+	session.Total = len(progress.Riddles)
+	progress.startRound(0, session)
+
+	return progress, notice, nil
+}
+
+// startRound queues up the riddles of round i and sets its clock.
+func (p *Progress) startRound(i int, session *Session) {
+	first := 0
+	for _, round := range p.Rounds[:i] {
+		first += round.Riddles
+	}
+
+	p.Round = i
+	p.Queue = nil
+	for j := first; j < first+p.Rounds[i].Riddles; j++ {
+		p.Queue = append(p.Queue, j)
+	}
+	p.Deferred = nil
+	p.FinalPass = false
+	p.Remaining = p.Rounds[i].Seconds
+	p.RoundStartScore = session.Score
+	p.RoundStartAnswered = session.Answered
+}
+
+// runRiddles asks the riddles of the current round at the terminal until
+// the round is over, returning the reason it ended.
+func runRiddles(g *game, term *terminal) string {
+	green := color.New(color.FgGreen).SprintFunc()
+	blue := color.New(color.FgBlue).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	tooLate := func() string {
+		term.Println(red("\nTime's up! That answer came in too late."))
+		return endTimedOut
+	}
+
+	// Riddles are asked in order, then any that were passed get a second
+	// round at the end if there is still time.
+	progress := g.session.Progress
+	for {
+		g.waitWhilePaused()
+		finalPass := progress.FinalPass
+		i, reason := g.question()
+		if reason == endTimedOut {
+			term.Println(red("\nTime's up!"))
+		}
+		if reason != "" {
+			return reason
+		}
+		if progress.FinalPass != finalPass {
+			term.Println(yellow("\nBack to the riddles you passed."))
+		}
+
+		riddle := progress.Riddles[i]
+		term.Printf("\n%s %s %s\n", green("Question "+fmt.Sprintf("%d:", i+1)), yellow(fmt.Sprintf("[%s, %d points]", riddleDifficulty(riddle), riddlePoints(riddle))), riddle.Question)
+		if len(riddle.Hints) > 0 {
+			term.Printf("(Type 'hint' for a hint, %d points each)\n", g.settings.HintCost)
+		}
+
+	prompt:
+		for {
+			term.Print(green("Enter your guess: "))
+			guess := term.readLine()
+			guess = strings.TrimSpace(guess)
+
+			// An answer typed after the clock ran out doesn't count, nor
+			// does one typed while the game is paused
+			if g.timer().expired() {
+				return tooLate()
+			}
+			if g.paused() {
+				term.Println(yellow("The game is paused, so that didn't count. Wait for the admin to resume it."))
+				continue
+			}
+
+			switch strings.ToLower(guess) {
+			case "":
+				continue
+			case "help":
+				term.Println(yellow(gameHelp))
+			case "hint":
+				revealHint(g, term)
+			case "pass":
+				if err := g.pass(); err == errTimeUp {
+					return tooLate()
+				} else if err != nil {
+					term.Println(red("This riddle was already passed once. Answer it or type 'skip'."))
+					continue
+				}
+				term.Println(blue("Passed. You can come back to this riddle at the end."))
+				term.Printf("Team %s Score: %d\n", g.team.Name, g.session.Score)
+				break prompt
+			case "skip":
+				if err := g.skip(); err != nil {
+					return tooLate()
+				}
+				term.Println(red(fmt.Sprintf("Skipped (-%d points).", g.settings.SkipPenalty)))
+				if g.settings.RevealPolicy == revealImmediately {
+					term.Println(blue("The correct answer was: ", riddle.Answer))
+				}
+				term.Printf("Team %s Score: %d\n", g.team.Name, g.session.Score)
+				break prompt
+			case "quit":
+				term.Print(red("Are you sure you want to end the game? (y/n): "))
+				confirmation := term.readLine()
+				if strings.TrimSpace(strings.ToLower(confirmation)) == "y" {
+					return endQuit
+				}
+			default:
+				correct, err := g.answer(guess)
+				if err != nil {
+					return tooLate()
+				}
+				if correct {
+					term.Println(blue("Correct! You solved the riddle!"))
+				} else {
+					term.Println(red("Incorrect guess!"))
+					if g.settings.RevealPolicy == revealImmediately {
+						term.Println(blue("The correct answer was: ", riddle.Answer))
+					}
+					drawHangman(g.session.WrongGuesses, term)
+				}
+				term.Printf("Team %s Score: %d  %s %s\n", g.team.Name, g.session.Score, yellow("Time left:"), formatDuration(g.remaining()))
+
+				if g.hanged() {
+					term.Println(red("You've been hanged!"))
+					return endHanged
+				}
+				break prompt
+			}
+		}
+	}
+}
+
+// playGame plays a team's game at the terminal, round by round, and saves
+// the finished session.
+func playGame(g *game, gameDuration time.Duration, term *terminal) error {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	resumed := g.session.Progress != nil
+	notice, err := g.start(gameDuration)
+	if err != nil {
+		return err
+	}
+	if notice != "" {
+		term.Println(red(notice))
+	}
+
+	progress := g.session.Progress
+	if resumed {
+		term.Printf("\n%s Picking up at riddle %d with %s left.\n\n", yellow("Resuming your game."), g.session.Answered+1, yellow(formatDuration(time.Duration(progress.Remaining*float64(time.Second)))))
+	} else if !progress.Themed {
+		// Display the total time allotted
+		term.Printf("\n%s You will have %s to solve all riddles.\n\n", yellow("Time Allotted:"), yellow(formatDuration(gameDuration)))
+	}
+	term.Println(yellow("Type 'help' at any question to see the available commands."))
+
+	for {
+		plan := progress.Rounds[progress.Round]
+		if progress.Themed {
+			term.Printf("\n%s\n", yellow(fmt.Sprintf("=== Round %d of %d: %s ===", progress.Round+1, len(progress.Rounds), plan.Name)))
+			term.Printf("%s riddles, %s\n", yellow(fmt.Sprint(plan.Riddles)), yellow(formatDuration(time.Duration(progress.Remaining*float64(time.Second)))))
+		}
+
+		// Start the timer with whatever is left of the round
+		g.beginRound()
+		reason := runRiddles(g, term)
+		more := g.endRound(reason)
+
+		if progress.Themed {
+			roundResult := g.session.Rounds[len(g.session.Rounds)-1]
+			term.Printf("%s %d points, %d/%d answered\n", yellow(fmt.Sprintf("End of %s:", roundResult.Name)), roundResult.Score, roundResult.Answered, roundResult.Total)
+		} else if reason == endTimedOut {
+			term.Println(red("The game is over."))
+		}
+		if !more {
+			break
+		}
+	}
+	g.finish()
+	return nil
+}
+
+// revealHint shows the riddle's next hint and what it cost the team.
+func revealHint(g *game, term *terminal) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	hint, err := g.hint()
+	switch err {
+	case nil:
+	case errNoHints:
+		term.Println(red("There are no hints for this riddle."))
+		return
+	case errHintsUsed:
+		term.Println(red("You've already seen every hint for this riddle."))
+		return
+	default:
+		term.Println(red("\nTime's up!"))
+		return
+	}
+
+	used := g.session.Progress.Results[g.asking].HintsUsed
+	total := len(g.session.Progress.Riddles[g.asking].Hints)
+	term.Printf("%s %s %s\n", yellow(fmt.Sprintf("Hint %d/%d:", used, total)), hint, red(fmt.Sprintf("(-%d points)", g.settings.HintCost)))
+}
+
+// A race pits teams against each other on the game server: every team in
+// it is asked the same riddle at the same time, the first to solve it gets
+// the points, and everyone sees who did. Races are made up in the lobby as
+// teams type 'run', RaceTeams at a time.
+type race struct {
+	mu       sync.Mutex
+	racers   []*racer
+	riddles  []Riddle
+	settings GameSettings
+
+	current int       // Index of the riddle being raced for, or -1 between riddles
+	asked   time.Time // When it was asked
+	solved  bool      // Whether someone has solved it
+	settled chan bool // Signalled once it is solved or nobody is left to solve it
+	done    chan bool // Closed when the race is over
+
+	outbox []raceMessage // Messages to send once mu is released
+}
+
+// raceMessage is a line of text for one team in a race.
+type raceMessage struct {
+	to   *racer
+	text string
+}
+
+// racer is a team in a race.
+type racer struct {
+	g      *game
+	term   *terminal
+	joined chan *race // Receives the race once it starts, or nil if it can't
+
+	out    bool           // Hanged or gone, so no more guesses
+	left   bool           // Gone from the server
+	result QuestionResult // On the riddle being raced for
+}
+
+// raceLobby holds the teams waiting for enough others to race.
+var raceLobby struct {
+	sync.Mutex
+	waiting []*racer
+}
+
+// raceLobbyTimeout is how long a team waits for others before giving up.
+const raceLobbyTimeout = 5 * time.Minute
+
+// errLobbyTimeout is why a team left the race lobby without racing.
+var errLobbyTimeout = errors.New("not enough teams joined the race in time")
+
+// errRaceAtTerminals turns away browser players in race mode; a resumed
+// game still plays on solo.
+var errRaceAtTerminals = errors.New("race mode is on, and races are only run at the game server's terminals")
+
+// leaveLobby takes a waiting team out of the race lobby. It reports false
+// if the team has already been put in a race.
+func leaveLobby(p *racer) bool {
+	raceLobby.Lock()
+	defer raceLobby.Unlock()
+	i := slices.Index(raceLobby.waiting, p)
+	if i < 0 {
+		return false
+	}
+	raceLobby.waiting = slices.Delete(raceLobby.waiting, i, i+1)
+	return true
+}
+
+// joinRace puts a team's game in the next race and plays it at the
+// terminal until the race is over. While it waits for other teams, the
+// team can type 'leave', and it gives up after raceLobbyTimeout; a team
+// whose terminal goes away is taken out of the lobby.
+func joinRace(g *game, term *terminal) error {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	p := &racer{g: g, term: term, joined: make(chan *race, 1)}
+	raceLobby.Lock()
+	raceLobby.waiting = append(raceLobby.waiting, p)
+	waiting := raceLobby.waiting
+	if len(waiting) >= g.settings.RaceTeams {
+		raceLobby.waiting = nil
+	}
+	raceLobby.Unlock()
+
+	if len(waiting) >= g.settings.RaceTeams {
+		r, err := newRace(waiting, g.settings)
+		for _, q := range waiting {
+			q.joined <- r
+		}
+		if err != nil {
+			return err
+		}
+		go r.run()
+	} else {
+		term.Println(yellow(fmt.Sprintf("\nRace mode! Waiting for %d more team(s) to join the race... (type 'leave' to stop waiting)", g.settings.RaceTeams-len(waiting))))
+	}
+
+	left, stopWatching := term.watchLeave(yellow("Still waiting for other teams. Type 'leave' to stop waiting."))
+	defer stopWatching()
+	timeout := time.After(raceLobbyTimeout)
+	for {
+		select {
+		case r := <-p.joined:
+			stopWatching()
+			if r == nil {
+				return errors.New("the race couldn't be started")
+			}
+			r.play(p)
+			return nil
+		case <-left:
+			if leaveLobby(p) {
+				return errLeftLobby
+			}
+			// Already put in a race, which notices a lost terminal once
+			// it starts
+			left = nil
+		case <-timeout:
+			if leaveLobby(p) {
+				return errLobbyTimeout
+			}
+		}
+	}
+}
+
+// newRace picks the riddles for a race between the racers and puts their
+// games in play.
+func newRace(racers []*racer, settings GameSettings) (*race, error) {
+	seed := settings.Seed
+	if !settings.competition() {
+		seed = time.Now().UnixNano()
+	}
+	pool, err := riddlePool(seed, settings.RiddleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching riddles: %v", err)
+	}
+	selected := selectRiddles(pool, settings.RaceRiddles, settings)
+	arrangeRiddles(selected, 0, settings.RampUp)
+
+	for _, p := range racers {
+		p.g.begin()
+		p.g.session.Seed = seed
+		p.g.session.Total = len(selected)
+	}
+	return &race{racers: racers, riddles: selected, settings: settings, current: -1,
+		settled: make(chan bool, 1), done: make(chan bool)}, nil
+}
+
+// run asks the race's riddles one at a time, each until it is solved, its
+// time runs out or nobody is left to solve it.
+func (r *race) run() {
+	green := color.New(color.FgGreen).SprintFunc()
+	blue := color.New(color.FgBlue).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	r.mu.Lock()
+	var names []string
+	for _, p := range r.racers {
+		names = append(names, p.g.team.Name)
+	}
+	r.broadcast(yellow(fmt.Sprintf("\nThe race is on: %s!", strings.Join(names, " vs "))))
+	r.broadcast(fmt.Sprintf("%d riddles, %s each. The first team to solve a riddle gets its points; wrong guesses still cost a life.", len(r.riddles), formatDuration(r.settings.RaceTime)))
+	r.unlock()
+
+	for i, riddle := range r.riddles {
+		r.mu.Lock()
+		if r.inRace() == 0 {
+			r.unlock()
+			break
+		}
+		select {
+		case <-r.settled: // Left over from a riddle settled as its time ran out
+		default:
+		}
+		r.current, r.asked, r.solved = i, time.Now(), false
+		for _, p := range r.racers {
+			p.result = QuestionResult{Question: riddle.Question, Answer: riddle.Answer}
+			if !p.out {
+				// The race doesn't wait on the write
+				go saveSessionEventToFirebase(p.g.id, p.g.stamp(SessionEvent{Type: playAsked, Riddle: i + 1, Question: riddle.Question}))
+			}
+		}
+		r.broadcast(fmt.Sprintf("\n%s %s %s", green(fmt.Sprintf("Question %d:", i+1)), yellow(fmt.Sprintf("[%s, %d points]", riddleDifficulty(riddle), riddlePoints(riddle))), riddle.Question))
+		r.unlock()
+
+		select {
+		case <-r.settled:
+		case <-time.After(r.settings.RaceTime):
+		}
+
+		r.mu.Lock()
+		if !r.solved {
+			r.broadcast(red("Nobody solved it!"))
+			if r.settings.RevealPolicy == revealImmediately {
+				r.broadcast(blue("The correct answer was: ", riddle.Answer))
+			}
+		}
+		r.current = -1
+		var scores []string
+		for _, p := range r.racers {
+			if p.left {
+				continue
+			}
+			session := p.g.session
+			if p.result.Guess != "" {
+				session.Answered++
+			} else {
+				p.result.Skipped = true
+			}
+			if !p.result.Correct {
+				p.result.Seconds = time.Since(r.asked).Seconds()
+			}
+			session.Results = append(session.Results, p.result)
+			scores = append(scores, fmt.Sprintf("%s %d", p.g.team.Name, session.Score))
+		}
+		r.broadcast(yellow("Scores: ") + strings.Join(scores, ", "))
+		r.unlock()
+
+		time.Sleep(2 * time.Second) // A moment to take in the outcome before the next riddle
+	}
+	r.finish()
+}
+
+// finish saves every team's game and shows the final standings.
+func (r *race) finish() {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	r.mu.Lock()
+	var finishers []*racer
+	for _, p := range r.racers {
+		if !p.left {
+			finishers = append(finishers, p)
+		}
+	}
+	r.unlock()
+
+	for _, p := range finishers {
+		p.g.finish()
+	}
+	sort.SliceStable(finishers, func(i, j int) bool {
+		return finishers[i].g.session.Score > finishers[j].g.session.Score
+	})
+
+	r.mu.Lock()
+	defer r.unlock()
+	r.broadcast(yellow("\nThe race is over! Final scores:"))
+	for i, p := range finishers {
+		r.broadcast(fmt.Sprintf("%d. %s  %d points", i+1, p.g.team.Name, p.g.session.Score))
+	}
+	r.broadcast(yellow("Press Enter to see how your team did."))
+	close(r.done)
+}
+
+// play takes the team's guesses at the terminal until the race is over.
+func (r *race) play(p *racer) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	// If the terminal goes away mid-race, the others race on without it
+	defer func() {
+		select {
+		case <-r.done:
+		default:
+			r.leave(p)
+		}
+	}()
+
+	for {
+		guess := strings.TrimSpace(p.term.readLine())
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+
+		switch strings.ToLower(guess) {
+		case "":
+		case "help":
+			p.term.Println(yellow("Type your guess at the riddle being asked. The first team to solve it gets the points."))
+		default:
+			r.guess(p, guess)
+		}
+	}
+}
+
+// guess checks a team's guess at the riddle being raced for.
+func (r *race) guess(p *racer, guess string) {
+	blue := color.New(color.FgBlue).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	r.mu.Lock()
+	defer r.unlock()
+	if p.out {
+		r.tell(p, red("Your team is out of the race."))
+		return
+	}
+	if r.current < 0 || r.solved {
+		r.tell(p, red("Wait for the next riddle."))
+		return
+	}
+
+	riddle := r.riddles[r.current]
+	p.result.Guess = guess
+	matched, ok := matchRiddle(guess, riddle, p.g.settings)
+	defer func() {
+		go saveSessionEventToFirebase(p.g.id, p.g.stamp(SessionEvent{Type: playAnswered, Riddle: r.current + 1, Question: riddle.Question, Guess: guess, Correct: ok}))
+	}()
+	if ok {
+		r.solved = true
+		p.result.Correct = true
+		p.result.Matched = matched
+		p.result.Points = riddlePoints(riddle)
+		p.result.Seconds = time.Since(r.asked).Seconds()
+		addPoints(p.g.team, p.g.session, riddlePoints(riddle))
+		r.tell(p, blue("Correct! You solved the riddle first!"))
+		r.broadcastExcept(p, red(fmt.Sprintf("Team %s solved it first!", p.g.team.Name)))
+		if r.settings.RevealPolicy == revealImmediately {
+			r.broadcastExcept(p, blue("The correct answer was: ", riddle.Answer))
+		}
+		r.settle()
+		return
+	}
+
+	p.g.session.WrongGuesses++
+	r.tell(p, red("Incorrect guess!"))
+	r.tell(p, hangmanStages[p.g.session.WrongGuesses])
+	r.broadcastExcept(p, fmt.Sprintf("Team %s guessed wrong.", p.g.team.Name))
+	if p.g.hanged() {
+		p.out = true
+		p.g.session.EndReason = endHanged
+		r.tell(p, red("You've been hanged! Your team is out, but you can watch the race to the end."))
+		r.broadcastExcept(p, red(fmt.Sprintf("Team %s has been hanged!", p.g.team.Name)))
+		if r.inRace() == 0 {
+			r.settle()
+		}
+	}
+}
+
+// leave takes a team that has gone away out of the race.
+func (r *race) leave(p *racer) {
+	r.mu.Lock()
+	defer r.unlock()
+	p.out = true
+	p.left = true
+	r.broadcast(fmt.Sprintf("\nTeam %s has left the race.", p.g.team.Name))
+	if r.inRace() == 0 {
+		r.settle()
+	}
+}
+
+// settle ends the riddle being raced for early.
+func (r *race) settle() {
+	select {
+	case r.settled <- true:
+	default:
+	}
+}
+
+// inRace counts the teams still able to guess. The caller must hold r.mu.
+func (r *race) inRace() int {
+	n := 0
+	for _, p := range r.racers {
+		if !p.out {
+			n++
+		}
+	}
+	return n
+}
+
+// tell queues text for a team, to be sent when r.mu is released. The
+// caller must hold r.mu.
+func (r *race) tell(p *racer, text string) {
+	r.outbox = append(r.outbox, raceMessage{to: p, text: text})
+}
+
+// broadcast queues text for every team still at the race. The caller must
+// hold r.mu.
+func (r *race) broadcast(text string) {
+	r.broadcastExcept(nil, text)
+}
+
+func (r *race) broadcastExcept(except *racer, text string) {
+	for _, p := range r.racers {
+		if p != except && !p.left {
+			r.tell(p, text)
+		}
+	}
+}
+
+// unlock releases r.mu, then sends the messages queued while it was held,
+// so a slow terminal never holds up the race itself.
+func (r *race) unlock() {
+	outbox := r.outbox
+	r.outbox = nil
+	r.mu.Unlock()
+	for _, message := range outbox {
+		message.to.term.Println(message.text)
+	}
+}
+
+// The stages of an event run from the admin tool. With no event set up,
+// teams start their games whenever they like.
+const (
+	eventOpen      = "open"      // Teams can log in and wait in the lobby
+	eventCountdown = "countdown" // Games start together at the end of the countdown
+	eventRunning   = "running"
+	eventPaused    = "paused" // Teams are held in the lobby and games in play are paused
+	eventClosed    = "closed" // No more games can be played
+)
+
+// Why a game was kept from starting by the event.
+var (
+	errEventClosed  = errors.New("the event is closed")
+	errEventStarted = errors.New("the event has already started, so new games can't join it")
+	errLeftLobby    = errors.New("you left the lobby") // Also the race's lobby
+)
+
+// Event is the admin's control over when games start.
+type Event struct {
+	State    string    `firestore:"state"`
+	StartsAt time.Time `firestore:"startsAt"` // End of the countdown
+}
+
+// stage is the event's state as of now, with a countdown that has run out
+// counting as running.
+func (e Event) stage() string {
+	if e.State == eventCountdown && !time.Now().Before(e.StartsAt) {
+		return eventRunning
+	}
+	return e.State
+}
+
+// getEventFromFirebase returns the event, or no event if none is set up.
+func getEventFromFirebase() (Event, error) {
+	ctx := context.Background()
+	client, err := firebaseApp.Firestore(ctx)
+	if err != nil {
+		return Event{}, fmt.Errorf("error creating Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	var event Event
+	doc, err := client.Collection("game_settings").Doc("event").Get(ctx)
+	if doc != nil && !doc.Exists() {
+		return event, nil
+	}
+	if err != nil {
+		return event, fmt.Errorf("error retrieving event: %v", err)
+	}
+	if err := doc.DataTo(&event); err != nil {
+		return event, fmt.Errorf("error converting document data to event: %v", err)
+	}
+	return event, nil
+}
+
+// eventWatch shares the event, polled from Firebase, between every game
+// waiting on it.
+var eventWatch struct {
+	sync.Mutex
+	once    sync.Once
+	event   Event
+	changed chan struct{} // Closed when the event next changes
+}
+
+// currentEvent returns the event and a channel that is closed when it
+// changes.
+func currentEvent() (Event, <-chan struct{}) {
+	eventWatch.once.Do(func() {
+		event, err := getEventFromFirebase()
+		if err != nil {
+			log.Printf("Error getting event: %v\n", err)
+		}
+		eventWatch.event = event
+		eventWatch.changed = make(chan struct{})
+		go pollEvent(time.Second)
+	})
+
+	eventWatch.Lock()
+	defer eventWatch.Unlock()
+	return eventWatch.event, eventWatch.changed
+}
+
+// pollEvent checks the event for changes every interval.
+func pollEvent(interval time.Duration) {
+	for range time.Tick(interval) {
+		event, err := getEventFromFirebase()
+		if err != nil {
+			log.Printf("Error getting event: %v\n", err)
+			continue
+		}
+
+		eventWatch.Lock()
+		if event.State != eventWatch.event.State || !event.StartsAt.Equal(eventWatch.event.StartsAt) {
+			eventWatch.event = event
+			close(eventWatch.changed)
+			eventWatch.changed = make(chan struct{})
+		}
+		eventWatch.Unlock()
+	}
+}
+
+// waitForEvent holds a game in the lobby until the event starts, so every
+// team's game starts at once, or until left is closed by the team leaving.
+// news is called whenever there is something new to tell the waiting team:
+// the event's stage and, in a countdown, the seconds left. A resumed game
+// can rejoin a running event, but a new one must have been waiting when it
+// started.
+func waitForEvent(resuming bool, left <-chan struct{}, news func(stage string, startsIn int)) error {
+	waited := false
+	lastStage, lastSeconds := "", 0
+	for {
+		event, changed := currentEvent()
+		stage := event.stage()
+		switch stage {
+		case "":
+			return nil
+		case eventClosed:
+			return errEventClosed
+		case eventRunning:
+			if waited || resuming {
+				return nil
+			}
+			return errEventStarted
+		}
+		waited = true
+
+		// Counting down, wake up on every second to tell the team
+		wait := time.Minute
+		seconds := 0
+		if stage == eventCountdown {
+			left := time.Until(event.StartsAt)
+			seconds = int(math.Ceil(left.Seconds()))
+			wait = left - time.Duration(seconds-1)*time.Second
+		}
+		if stage != lastStage || (seconds != lastSeconds && (seconds <= 10 || seconds%10 == 0)) {
+			news(stage, seconds)
+			lastStage, lastSeconds = stage, seconds
+		}
+
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-left:
+			return errLeftLobby
+		}
+	}
+}
+
+// scoringText describes a scoring policy for the login message.
+func scoringText(scoring string) string {
+	switch scoring {
+	case scoreBest:
+		return "best"
+	case scoreAverage:
+		return "average of every"
+	default:
+		return "latest"
+	}
+}
+
+// endReasonText describes why a session ended, for the summary screen.
+func endReasonText(reason string) string {
+	switch reason {
+	case endCompleted:
+		return "All riddles answered"
+	case endHanged:
+		return "Hanged"
+	case endTimedOut:
+		return "Time ran out"
+	case endQuit:
+		return "Quit"
+	case endAbandoned:
+		return "Abandoned"
+	case endInterrupted:
+		return "Interrupted"
+	case endTerminated:
+		return "Terminated"
+	case endDisconnected:
+		return "Disconnected"
+	default:
+		return reason
+	}
+}
+
+// shorten trims text to at most n runes for the summary table.
+func shorten(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+func displaySummary(session *Session, settings GameSettings, term *terminal) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	blue := color.New(color.FgBlue).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	if session.EndReason == endHanged || session.EndReason == endTimedOut {
+		displaygameoverLogo(term)
+	}
+
+	term.Println(yellow("\n\t\tGame Summary\n"))
+	term.Printf("%s %s\n", green("Team:"), session.Team)
+	term.Printf("%s %s\n", green("Result:"), endReasonText(session.EndReason))
+	term.Printf("%s %d/%d\n", green("Riddles answered:"), session.Answered, session.Total)
+	term.Printf("%s %d\n", green("Wrong guesses:"), session.WrongGuesses)
+	term.Printf("%s %d\n", green("Lives remaining:"), session.LivesLeft)
+	term.Printf("%s %d\n", green("Final score:"), session.Score)
+	if session.Rank > 0 {
+		term.Printf("%s %d\n", green("Rank:"), session.Rank)
+	}
+
+	if len(session.Rounds) > 0 {
+		term.Println(blue(fmt.Sprintf("\n%-25s %-15s %8s %6s", "Round", "Result", "Answered", "Points")))
+		for _, round := range session.Rounds {
+			term.Printf("%-25s %-15s %4d/%-3d %6d\n", shorten(round.Name, 25), endReasonText(round.EndReason), round.Answered, round.Total, round.Score)
+		}
+	}
+
+	if len(session.Results) == 0 {
+		term.Println()
+		return
+	}
+
+	term.Println(blue(fmt.Sprintf("\n%-3s %-40s %-15s %-15s %-8s %6s %5s %6s", "#", "Riddle", "Your answer", "Answer", "Result", "Time", "Hints", "Points")))
+	for i, result := range session.Results {
+		outcome := red(fmt.Sprintf("%-8s", "wrong"))
+		if result.Correct {
+			outcome = blue(fmt.Sprintf("%-8s", "correct"))
+		} else if result.Skipped {
+			outcome = yellow(fmt.Sprintf("%-8s", "skipped"))
+		}
+		// Answers to missed riddles stay hidden when the event says so
+		answer := result.Answer
+		if !result.Correct && settings.RevealPolicy == revealNever {
+			answer = "-"
+		}
+		term.Printf("%-3d %-40s %-15s %-15s %s %5.0fs %5d %6d\n", i+1, shorten(result.Question, 40), shorten(result.Guess, 15), shorten(answer, 15), outcome, result.Seconds, result.HintsUsed, result.Points)
+	}
+	term.Println()
+}
+
+// foldAnswer puts s in NFKC form and case-folds it, so full-width and
+// compatibility characters compare equal to their plain forms. With
+// ignoreAccents set, diacritics are dropped as well.
+func foldAnswer(s string, ignoreAccents bool) string {
+	s = cases.Fold().String(norm.NFKC.String(s))
+	if !ignoreAccents {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFC.String(b.String())
+}
+
+// ignorable reports whether r plays no part in comparing answers.
+func ignorable(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.Is(unicode.Cf, r)
+}
+
+// Helper function to normalize strings for comparison
+func normalizeString(s string, ignoreAccents bool) string {
+	// Fold the string and remove all whitespace and punctuation
+	return strings.Map(func(r rune) rune {
+		if ignorable(r) {
+			return -1
+		}
+		return r
+	}, foldAnswer(s, ignoreAccents))
+}
+
+var leadingArticles = []string{"a", "an", "the"}
+
+// canonicalAnswer folds s, drops punctuation and a leading article, and
+// joins the remaining words, so "The Secret!" becomes "secret".
+func canonicalAnswer(s string, ignoreAccents bool) string {
+	s = strings.Map(func(r rune) rune {
+		if ignorable(r) || unicode.IsSymbol(r) {
+			return ' '
+		}
+		return r
+	}, foldAnswer(s, ignoreAccents))
+
+	words := strings.Fields(s)
+	if len(words) > 1 {
+		for _, article := range leadingArticles {
+			if words[0] == article {
+				words = words[1:]
+				break
+			}
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// singular strips a simple English plural ending from s.
+func singular(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies") && len(s) > 4:
+		return strings.TrimSuffix(s, "ies") + "y"
+	case strings.HasSuffix(s, "sses"), strings.HasSuffix(s, "xes"), strings.HasSuffix(s, "ches"), strings.HasSuffix(s, "shes"):
+		return strings.TrimSuffix(s, "es")
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss") && len(s) > 3:
+		return strings.TrimSuffix(s, "s")
+	}
+	return s
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// allowedTypos is how many edits a guess may be away from an answer of the
+// given length: one per six letters normally, one per four when lenient.
+func allowedTypos(length int, strictness string) int {
+	switch strictness {
+	case matchExact:
+		return 0
+	case matchLenient:
+		return length / 4
+	default:
+		return length / 6
+	}
+}
+
+// answerMatches reports whether guess is an acceptable answer for a riddle
+// with the given strictness.
+func answerMatches(guess, answer, strictness string, ignoreAccents bool) bool {
+	if strictness == matchExact {
+		return normalizeString(guess, ignoreAccents) == normalizeString(answer, ignoreAccents)
+	}
+
+	g, a := singular(canonicalAnswer(guess, ignoreAccents)), singular(canonicalAnswer(answer, ignoreAccents))
+	if g == "" {
+		return false
+	}
+	if g == a {
+		return true
+	}
+	return editDistance(g, a) <= allowedTypos(len([]rune(a)), strictness)
+}
+
+// matchRiddle checks guess against the riddle's answer, its aliases and its
+// patterns in that order, returning whichever one it matched.
+func matchRiddle(guess string, riddle Riddle, settings GameSettings) (string, bool) {
+	for _, answer := range append([]string{riddle.Answer}, riddle.Aliases...) {
+		if answer != "" && answerMatches(guess, answer, riddle.Strictness, settings.IgnoreAccents) {
+			return answer, true
+		}
+	}
+
+	for _, pattern := range riddle.Patterns {
+		re, err := regexp.Compile("(?i)^(?:" + pattern + ")$")
+		if err != nil {
+			log.Printf("Skipping invalid answer pattern %q: %v\n", pattern, err)
+			continue
+		}
+		if re.MatchString(norm.NFKC.String(strings.TrimSpace(guess))) {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// userInterface runs the login and game for one team on term. A server
+// that has already checked the admin password passes adminpasswordVerified
+// so it isn't asked at every station.
+func userInterface(term *terminal, adminpasswordVerified bool) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+	blue := color.New(color.FgBlue).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	displaysolarisLogo(term)
+
+	// The admin's announcements are shown on a line of their own; anything
+	// the team has half typed is still there to finish.
+	defer listen(func(announcement Announcement) {
+		term.Printf("\n%s %s\n", yellow("Announcement:"), announcement.Text)
+	})()
+
+	// Fetch approved teams from Firebase
+	approvedTeams, err := getApprovedTeamsFromFirebase()
+	if err != nil {
+		log.Printf("Error fetching approved teams: %v\n", err)
+		term.Println(red("The game is unavailable right now. Contact admin."))
+		return
+	}
+
+	var teamName string
+	var team *Team
+	var resumable *Session
+	teamEntered := false
+	passwordVerified := false
+
+	for {
+		if !adminpasswordVerified {
+			if !checkAdminPassword(term) {
+				continue
+			}
+
+			adminpasswordVerified = true // Mark the password as verified
 		}
 		if !teamEntered {
 			// Prompt the user to enter their team name
-			fmt.Print(green("Enter your team name: "))
-			teamName, _ = reader.ReadString('\n')
+			term.Print(green("Enter your team name: "))
+			teamName = term.readLine()
 			teamName = strings.TrimSpace(strings.ToLower(teamName)) // Remove leading/trailing spaces; no lowercase conversion
 
-			// Validate the team name exactly as entered (case-sensitive)
-			if !validateTeam(approvedTeams, teamName) {
-				fmt.Println(red("Your team is not on the approved list. Contact admin for access."))
-				continue
+			// Validate the team name exactly as entered (case-sensitive)
+			if !validateTeam(approvedTeams, teamName) {
+				term.Println(red("Your team is not on the approved list. Contact admin for access."))
+				continue
+			}
+
+			// Fetch the team from Firebase (no lowercase conversion)
+			existingTeam, err := getTeamFromFirebase(teamName)
+			found := err == nil
+
+			// An existing team proves it is who it says before it is kept
+			// from other terminals or its attempts are looked at
+			if found && existingTeam.Password != "" {
+				tries := 0
+				for !validatePassword(&existingTeam, term) {
+					tries++
+					if tries == maxPasswordTries {
+						term.Println(red("Too many incorrect passwords. Goodbye."))
+						return
+					}
+					term.Println(red("Incorrect password. Please try again."))
+				}
+			}
+			release, ok := claimTeam(teamName)
+			if !ok {
+				term.Println(red("Your team is already playing on another terminal."))
+				continue
+			}
+			defer release()
+
+			if found {
+				// Team exists, retrieve it from Firebase
+				team = &existingTeam
+
+				settings, err := getGameSettingsFromFirebase()
+				if err != nil {
+					log.Printf("Error getting game settings: %v. Using defaults.\n", err)
+				}
+				resumable, err = beginAttempt(team, settings)
+				if err != nil {
+					release()
+					term.Println(red(fmt.Sprintf("%s. Contact admin for access.", sentence(err))))
+					continue
+				}
+				if resumable != nil {
+					term.Println(blue("Existing team found with an unfinished game. Type 'run' to resume it."))
+				} else {
+					term.Println(blue(fmt.Sprintf("Existing team found. This is attempt %d; your score counts the %s attempt.", team.Attempts, scoringText(settings.Scoring))))
+				}
+			} else {
+				// Team doesn't exist, create a new team
+				team = &Team{Name: teamName, Score: 0, Attempts: 1}
+				term.Println(blue("Team not found. Creating a new team..."))
+				createPasswordForNewTeam(team, term)
+			}
+
+			teamEntered = true
+			passwordVerified = true
+		}
+
+		if passwordVerified {
+			// Game logic starts here
+			term.Print(green("Type 'run' to start the game or 'close' to exit: "))
+			command := term.readLine()
+			command = strings.TrimSpace(strings.ToLower(command))
+
+			if command == "run" {
+				inLobby := false
+				left, stopWatching := term.watchLeave(yellow("Still waiting for the event to start. Type 'leave' to leave the lobby."))
+				err := waitForEvent(resumable != nil, left, func(stage string, startsIn int) {
+					inLobby = true
+					switch stage {
+					case eventOpen:
+						term.Println(yellow("\nYou're in the lobby. Every team's game starts at once when the admin starts the event. Type 'leave' to leave it."))
+					case eventPaused:
+						term.Println(yellow("\nThe event is paused. Please wait..."))
+					case eventCountdown:
+						term.Println(yellow(fmt.Sprintf("Starting in %d...", startsIn)))
+					}
+				})
+				stopWatching()
+				if err != nil {
+					term.Println(red(sentence(err) + "."))
+					continue
+				}
+				if inLobby {
+					term.Println(green("\nGo!"))
+				}
+
+				stopUpdater := startScoreUpdater(team)
+
+				// Get game duration from Firebase
+				gameDuration, err := getGameDurationFromFirebase()
+				if err != nil {
+					log.Printf("Error getting game duration: %v. Using default of 5 minutes.\n", err)
+					gameDuration = 5 * time.Minute
+				}
+
+				settings, err := getGameSettingsFromFirebase()
+				if err != nil {
+					log.Printf("Error getting game settings: %v. Using defaults.\n", err)
+				}
+
+				session := resumable
+				if session == nil {
+					session = &Session{Team: team.Name, Attempt: team.Attempts, StartedAt: time.Now()}
+				}
+				g := newGame(team, session, settings)
+				// On the game server, a new game in race mode is played
+				// against other teams instead of the clock.
+				racing := serving && settings.Race && resumable == nil
+				addActiveGame(g, activeGame{stopUpdater: stopUpdater, racing: racing, notify: func(text string) {
+					term.Println(yellow("\n" + text))
+				}})
+				// If the terminal goes away mid-game, save it to be resumed
+				defer dropActiveGame(g, endDisconnected)
+
+				if racing {
+					err = joinRace(g, term)
+				} else {
+					err = playGame(g, gameDuration, term)
+				}
+				removeActiveGame(g)
+				stopUpdater()
+				if err == errLeftLobby || err == errLobbyTimeout {
+					term.Println(red(sentence(err) + "."))
+					continue
+				}
+				if err != nil {
+					log.Printf("Error starting game: %v\n", err)
+					term.Println(red("The game couldn't be started. Contact admin."))
+					return
+				}
+				displaySummary(session, settings, term)
+
+				for {
+					term.Print(green("Type 'close' to exit: "))
+					command := term.readLine()
+					command = strings.TrimSpace(strings.ToLower(command))
+
+					if command == "close" {
+						term.Println(blue("Exiting the game..."))
+						return
+					} else {
+						term.Println(red("Invalid command. Please type 'close'."))
+					}
+				}
+			} else if command == "close" {
+				term.Println(blue("Exiting..."))
+				break
+			} else {
+				term.Println(red("Invalid command"))
+			}
+		}
+	}
+}
+
+// checkAdminPassword asks for the admin password and reports whether it
+// was right.
+func checkAdminPassword(term *terminal) bool {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgHiRed).SprintFunc()
+
+	term.Print(green("Enter admin the password to start the game: "))
+	passwordEntered := term.readLine()
+	passwordEntered = strings.TrimSpace(passwordEntered)
+
+	correctPassword, err := getPasswordFromFirebase()
+	if err != nil {
+		term.Printf("Error retrieving password: %v\n", err)
+		return false
+	}
+
+	if passwordEntered != correctPassword {
+		term.Println(red("Incorrect password. Please try again."))
+		return false
+	}
+	return true
+}
+
+// terminal is where a team plays: the console the game was started from,
+// or a connection to the game server.
+type terminal struct {
+	in      *bufio.Reader
+	out     io.Writer
+	hangUp  func()      // Ends the terminal once its input is gone; never returns
+	reading chan string // A read in flight that nobody has taken the line from yet
+}
+
+// localTerminal is the console the game was started from. Closing its
+// input ends the program, saving any game in play.
+func localTerminal() *terminal {
+	return &terminal{
+		in:     bufio.NewReader(os.Stdin),
+		out:    os.Stdout,
+		hangUp: func() { shutdown(endDisconnected) },
+	}
+}
+
+func (t *terminal) Print(a ...interface{}) {
+	fmt.Fprint(t.out, a...)
+}
+
+func (t *terminal) Printf(format string, a ...interface{}) {
+	fmt.Fprintf(t.out, format, a...)
+}
+
+func (t *terminal) Println(a ...interface{}) {
+	fmt.Fprintln(t.out, a...)
+}
+
+// readLine reads the next line typed at the terminal, hanging up if there
+// are no more.
+func (t *terminal) readLine() string {
+	line, ok := <-t.nextLine()
+	if !ok {
+		t.hangUp()
+	}
+	t.reading = nil
+	return line
+}
+
+// nextLine returns a channel that gives the next line typed at the
+// terminal, or is closed if there are no more, for waiting on the
+// terminal alongside other things. A line not taken from it is left for
+// the next read, so none is lost; whoever takes one must clear t.reading.
+func (t *terminal) nextLine() <-chan string {
+	if t.reading == nil {
+		reading := make(chan string, 1)
+		t.reading = reading
+		go func() {
+			line, err := t.in.ReadString('\n')
+			if err != nil {
+				close(reading)
+				return
+			}
+			reading <- line
+		}()
+	}
+	return t.reading
+}
+
+// watchLeave closes the returned channel once the team types 'leave' or
+// the terminal goes away, until the returned stop function is called.
+// Anything else typed meanwhile is answered with reminder.
+func (t *terminal) watchLeave(reminder string) (<-chan struct{}, func()) {
+	left := make(chan struct{})
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case line, ok := <-t.nextLine():
+				if ok {
+					t.reading = nil
+					if strings.TrimSpace(strings.ToLower(line)) != "leave" {
+						t.Println(reminder)
+						continue
+					}
+				}
+				// A lost terminal hangs up at its next read
+				close(left)
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return left, func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+		})
+	}
+}
+
+// crlfWriter ends lines with "\r\n", as network terminals expect.
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (c crlfWriter) Write(p []byte) (int, error) {
+	if _, err := c.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// serving is set when the game is hosted for many terminals at once, which
+// is what lets teams race each other.
+var serving bool
+
+// serve hosts the game over TCP, so teams play from their own machines
+// with any telnet-style client, e.g. "telnet host 2323" or "nc host 2323",
+// and never need the game or its credentials. Unless httpAddr is empty, the
+// leaderboard page and web API are served there too. The admin password is asked once here
+// rather than at every station.
+func serve(addr, httpAddr string) {
+	blue := color.New(color.FgBlue).SprintFunc()
+	serving = true
+
+	console := localTerminal()
+	for !checkAdminPassword(console) {
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Error starting server: %v\n", err)
+	}
+	color.NoColor = false // Players get colors whatever the server's own output is
+	console.Println(blue(fmt.Sprintf("Serving the game on %s. Press Ctrl-C to stop.", listener.Addr())))
+
+	if httpAddr != "" {
+		feed := newLeaderboardFeed()
+		go feed.run(2 * time.Second)
+		listen(feed.announce)
+
+		mux := http.NewServeMux()
+		mux.Handle("/play", websocket.Handler(playOverWebSocket))
+		mux.HandleFunc("/api/leaderboard", feed.serveJSON)
+		mux.HandleFunc("/api/leaderboard/stream", feed.serveStream)
+		mux.Handle("/", http.FileServer(http.FS(leaderboardFiles)))
+		go func() {
+			if err := http.ListenAndServe(httpAddr, mux); err != nil {
+				log.Fatalf("Error serving HTTP: %v\n", err)
 			}
+		}()
+		console.Println(blue(fmt.Sprintf("Serving the leaderboard and web API on %s.", httpAddr)))
+	}
 
-			// Fetch the team from Firebase (no lowercase conversion)
-			existingTeam, err := getTeamFromFirebase(teamName)
-			if err == nil {
-				// Team exists, retrieve it from Firebase
-				team = &existingTeam
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Error accepting connection: %v\n", err)
+			continue
+		}
+		go serveConn(conn)
+	}
+}
 
-				// Check if attempts are increasing
-				if team.Attempts > 0 {
-					// If attempts are increasing, reset score to zero
-					team.Score = 0
-				}
-				team.Attempts++ // Increment attempts
-				fmt.Println(blue("Existing team found. Attempts incremented and score reset to 0."))
-			} else {
-				// Team doesn't exist, create a new team
-				team = &Team{Name: teamName, Score: 0, Attempts: 1}
-				fmt.Println(blue("Team not found. Creating a new team..."))
-				createPasswordForNewTeam(team, reader)
-				passwordVerified = true
+// The leaderboard page is built into the binary, so serving it needs
+// nothing but the game itself.
+//
+//go:embed index.html styles.css script.js images
+var leaderboardFiles embed.FS
+
+// leaderboardEntry is all the public leaderboard shows of a team. Nothing
+// else about teams, passwords least of all, leaves the server.
+type leaderboardEntry struct {
+	Rank         int     `json:"rank"`
+	Name         string  `json:"name"`
+	Score        int     `json:"score"`
+	WrongGuesses int     `json:"wrongGuesses"`
+	Seconds      float64 `json:"seconds"`
+	Hints        int     `json:"hints"`
+	Finished     bool    `json:"finished"`
+}
+
+// leaderboard lists the teams in ranked order for the public.
+func leaderboard(standings []ranking.Standing) []leaderboardEntry {
+	entries := make([]leaderboardEntry, len(standings))
+	for i, standing := range standings {
+		entries[i] = leaderboardEntry{
+			Rank:         standing.Rank,
+			Name:         standing.Team,
+			Score:        standing.Score,
+			WrongGuesses: standing.WrongGuesses,
+			Seconds:      math.Round(standing.Seconds),
+			Hints:        standing.Hints,
+			Finished:     !standing.FinishedAt.IsZero(),
+		}
+	}
+	return entries
+}
+
+// leaderboardFeed keeps the leaderboard up to date and pushes it to every
+// browser watching whenever it changes, along with the admin's
+// announcements.
+type leaderboardFeed struct {
+	mu           sync.Mutex
+	latest       []byte // The leaderboard as JSON
+	announcement []byte // The last announcement as JSON
+	watchers     map[chan feedEvent]bool
+}
+
+// feedEvent is one server-sent event: a leaderboard or an announcement.
+type feedEvent struct {
+	name string
+	data []byte
+}
+
+func newLeaderboardFeed() *leaderboardFeed {
+	return &leaderboardFeed{watchers: map[chan feedEvent]bool{}}
+}
+
+// run re-ranks the teams every interval, forever.
+func (f *leaderboardFeed) run(interval time.Duration) {
+	for {
+		settings, err := getGameSettingsFromFirebase()
+		if err != nil {
+			log.Printf("Error getting game settings: %v. Using defaults.\n", err)
+		}
+		standings, _, err := getStandings(settings)
+		if err != nil {
+			log.Printf("Error ranking teams for the leaderboard: %v\n", err)
+		} else {
+			f.update(leaderboard(standings))
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (f *leaderboardFeed) update(entries []leaderboardEntry) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		log.Printf("Error encoding the leaderboard: %v\n", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if bytes.Equal(data, f.latest) {
+		return
+	}
+	f.latest = data
+	f.publish(feedEvent{name: "leaderboard", data: data})
+}
+
+// announce passes an announcement from the admin on to every browser.
+func (f *leaderboardFeed) announce(announcement Announcement) {
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		log.Printf("Error encoding an announcement: %v\n", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.announcement = data
+	f.publish(feedEvent{name: "announcement", data: data})
+}
+
+// publish sends event to every watcher. The caller must hold f.mu.
+func (f *leaderboardFeed) publish(event feedEvent) {
+	for events := range f.watchers {
+		select {
+		case events <- event:
+		default:
+			// A watcher this far behind is cut off rather than holding
+			// up the rest; its browser reconnects by itself
+			delete(f.watchers, events)
+			close(events)
+		}
+	}
+}
+
+// watch returns the events a new watcher starts with, the current
+// leaderboard and the last announcement, and a channel of the ones that
+// follow, until stop is called.
+func (f *leaderboardFeed) watch() ([]feedEvent, chan feedEvent, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var current []feedEvent
+	if f.latest != nil {
+		current = append(current, feedEvent{name: "leaderboard", data: f.latest})
+	}
+	if f.announcement != nil {
+		current = append(current, feedEvent{name: "announcement", data: f.announcement})
+	}
+
+	events := make(chan feedEvent, 16)
+	f.watchers[events] = true
+	stop := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.watchers, events)
+	}
+	return current, events, stop
+}
+
+// serveJSON answers with the current leaderboard.
+func (f *leaderboardFeed) serveJSON(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	data := f.latest
+	f.mu.Unlock()
+	if data == nil {
+		data = []byte("[]")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// serveStream sends the leaderboard as server-sent events, once now and
+// again every time it changes, and the admin's announcements as they come.
+func (f *leaderboardFeed) serveStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	current, events, stop := f.watch()
+	defer stop()
+	for _, event := range current {
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, event.data)
+	}
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
 			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, event.data)
+			flusher.Flush()
+		}
+	}
+}
 
-			teamEntered = true
+// serveConn runs a game for the team on conn. A dropped connection aborts
+// the game in play so the team can resume it from another terminal.
+func serveConn(conn net.Conn) {
+	defer conn.Close()
+	log.Printf("Player connected from %s\n", conn.RemoteAddr())
+	defer log.Printf("Player at %s disconnected\n", conn.RemoteAddr())
+
+	term := &terminal{in: bufio.NewReader(conn), out: crlfWriter{conn}}
+	term.hangUp = runtime.Goexit // Runs userInterface's deferred calls, which save any game in play
+	userInterface(term, true)
+}
+
+// The web API lets a browser play the same game as the terminal, over a
+// WebSocket at /play. The browser sends one JSON message per move:
+//
+//	{"action": "login", "team": "...", "password": "..."}
+//	{"action": "start"}
+//	{"action": "question"}
+//	{"action": "answer", "guess": "..."}
+//	{"action": "hint"}, {"action": "skip"}, {"action": "pass"}
+//	{"action": "time"}, {"action": "quit"}
+//
+// and gets back one or more replies, each {"type": "...", "data": ...}, or
+// {"type": "error", "error": "..."} when the move isn't allowed. While an
+// event run by the admin hasn't started, "start" waits in the lobby,
+// replying "lobby" whenever there is news, before the game starts; only
+// {"action": "leave"} is taken there, which gives up waiting. In race
+// mode, only a resumed game can be started. While
+// the admin has the game paused, every move but "time" is refused; news
+// from the admin arrives as {"type": "notice", "data": "..."}, and their
+// announcements as {"type": "announcement", "data": {"text": "...", ...}}.
+
+// apiMessage is a move sent by a browser player.
+type apiMessage struct {
+	Action   string `json:"action"`
+	Team     string `json:"team,omitempty"`
+	Password string `json:"password,omitempty"`
+	Guess    string `json:"guess,omitempty"`
+}
+
+// apiReply is a message to a browser player.
+type apiReply struct {
+	Type  string      `json:"type"`
+	Error string      `json:"error,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// apiStatus is where the team stands, sent with most replies.
+type apiStatus struct {
+	Score        int     `json:"score"`
+	Answered     int     `json:"answered"`
+	Total        int     `json:"total"`
+	WrongGuesses int     `json:"wrongGuesses"`
+	LivesLeft    int     `json:"livesLeft"`
+	Remaining    float64 `json:"remaining"` // Seconds left in the round
+	Paused       bool    `json:"paused"`    // Stopped by the admin; moves wait until it resumes
+}
+
+type apiLogin struct {
+	Team     string `json:"team"`
+	Attempt  int    `json:"attempt"`
+	Resuming bool   `json:"resuming"`
+	Scoring  string `json:"scoring"`
+}
+
+type apiLobby struct {
+	State    string `json:"state"`              // open, countdown or paused
+	StartsIn int    `json:"startsIn,omitempty"` // Seconds left in the countdown
+}
+
+type apiStarted struct {
+	Notice string      `json:"notice,omitempty"`
+	Rounds []RoundPlan `json:"rounds,omitempty"` // Set for a game of themed rounds
+	Status apiStatus   `json:"status"`
+}
+
+type apiQuestion struct {
+	Number     int       `json:"number"`
+	Question   string    `json:"question"`
+	Difficulty string    `json:"difficulty"`
+	Points     int       `json:"points"`
+	Hints      int       `json:"hints"`
+	HintCost   int       `json:"hintCost"`
+	Round      string    `json:"round,omitempty"`
+	Revisit    bool      `json:"revisit"` // First of the riddles that were passed
+	Status     apiStatus `json:"status"`
+}
+
+type apiResult struct {
+	Correct bool      `json:"correct"`
+	Answer  string    `json:"answer,omitempty"` // Only when the event reveals answers immediately
+	Status  apiStatus `json:"status"`
+}
+
+type apiHint struct {
+	Hint   string    `json:"hint"`
+	Used   int       `json:"used"`
+	Total  int       `json:"total"`
+	Cost   int       `json:"cost"`
+	Status apiStatus `json:"status"`
+}
+
+// webPlayer is a browser playing over the web API.
+type webPlayer struct {
+	ws *websocket.Conn
+
+	team        *Team
+	resumable   *Session
+	game        *game
+	stopUpdater func()
+	release     func() // Gives up the team's claim when the browser goes away
+	moves       <-chan apiMessage
+
+	failedPasswords int // Wrong passwords given, up to maxPasswordTries
+}
+
+// playOverWebSocket runs the web API for one browser. A game left in play
+// when the browser goes away is saved so the team can resume it.
+func playOverWebSocket(ws *websocket.Conn) {
+	log.Printf("Browser player connected from %s\n", ws.Request().RemoteAddr)
+	moves := make(chan apiMessage)
+	player := &webPlayer{ws: ws, moves: moves}
+	defer listen(func(announcement Announcement) {
+		player.send("announcement", announcement)
+	})()
+	defer func() {
+		if player.release != nil {
+			player.release()
+		}
+	}()
+	defer func() {
+		if player.game != nil {
+			dropActiveGame(player.game, endDisconnected)
 		}
+	}()
 
-		if teamEntered && !passwordVerified {
-			if team.Password != "" {
-				// Validate existing password
-				if validatePassword(team, reader) {
-					passwordVerified = true
-				} else {
-					fmt.Println(red("Incorrect password. Please try again."))
+	// Moves are read on their own goroutine, so a team waiting in the
+	// lobby can still leave it
+	go player.receive(moves)
+	for msg := range moves {
+		player.handle(msg)
+	}
+}
+
+// receive reads the browser's moves into moves, closing it once the
+// browser goes away.
+func (p *webPlayer) receive(moves chan<- apiMessage) {
+	defer close(moves)
+	for {
+		var msg apiMessage
+		if err := websocket.JSON.Receive(p.ws, &msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				p.sendError(errors.New("messages must be JSON moves"))
+				continue
+			}
+			return
+		}
+		moves <- msg
+	}
+}
+
+// watchLobby closes the returned channel once the browser sends "leave" or
+// goes away, until the returned stop function is called. Any other move
+// meanwhile is refused.
+func (p *webPlayer) watchLobby() (<-chan struct{}, func()) {
+	left := make(chan struct{})
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case msg, ok := <-p.moves:
+				if ok && msg.Action != "leave" {
+					p.sendError(errors.New("you're in the lobby; send \"leave\" to leave it"))
 					continue
 				}
+				close(left)
+				return
+			case <-done:
+				return
 			}
 		}
+	}()
 
-		if passwordVerified {
-			// Game logic starts here
-			fmt.Print(green("Type 'run' to start the game or 'close' to exit: "))
-			command, _ := reader.ReadString('\n')
-			command = strings.TrimSpace(strings.ToLower(command))
+	var once sync.Once
+	return left, func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+		})
+	}
+}
 
-			if command == "run" {
-				startScoreUpdater(team)
+func (p *webPlayer) send(replyType string, data interface{}) {
+	if err := websocket.JSON.Send(p.ws, apiReply{Type: replyType, Data: data}); err != nil {
+		log.Printf("Error sending to browser player: %v\n", err)
+	}
+}
 
-				// Get game duration from Firebase
-				gameDuration, err := getGameDurationFromFirebase()
-				if err != nil {
-					log.Printf("Error getting game duration: %v. Using default of 5 minutes.\n", err)
-					gameDuration = 5 * time.Minute
-				}
+func (p *webPlayer) sendError(err error) {
+	if err := websocket.JSON.Send(p.ws, apiReply{Type: "error", Error: err.Error()}); err != nil {
+		log.Printf("Error sending to browser player: %v\n", err)
+	}
+}
 
-				// Display the total time allotted
-				minutes := int(gameDuration.Minutes())
-				seconds := int(gameDuration.Seconds()) % 60
-				fmt.Printf("\n%s You will have %s to solve all riddles.\n\n", yellow("Time Allotted:"), yellow(fmt.Sprintf("%dmin %dsec", minutes, seconds)))
+func (p *webPlayer) status() apiStatus {
+	session := p.game.session
+	return apiStatus{
+		Score:        session.Score,
+		Answered:     session.Answered,
+		Total:        session.Total,
+		WrongGuesses: session.WrongGuesses,
+		LivesLeft:    len(hangmanStages) - 1 - session.WrongGuesses,
+		Remaining:    p.game.remaining().Seconds(),
+		Paused:       p.game.paused(),
+	}
+}
 
-				// Start the timer with the duration from Firebase
-				startTimer(gameDuration)
+// handle makes one move for the player and replies to it.
+func (p *webPlayer) handle(msg apiMessage) {
+	switch msg.Action {
+	case "login":
+		p.login(msg.Team, msg.Password)
+		return
+	case "start":
+		p.start()
+		return
+	}
 
-				// Fetch riddles from Firebase or hardcoded ones
-				riddlesSubset, err := randomRiddles(15)
-				if err != nil {
-					log.Fatalf("Error fetching riddles: %v\n", err)
-				}
+	if p.game == nil {
+		p.sendError(errors.New("start a game first"))
+		return
+	}
+	g := p.game
+	if g.paused() && msg.Action != "time" {
+		p.sendError(errPaused)
+		return
+	}
+
+	switch msg.Action {
+	case "question":
+		p.next()
+	case "answer":
+		guess := strings.TrimSpace(msg.Guess)
+		if guess == "" {
+			p.sendError(errors.New("type an answer"))
+			return
+		}
+		riddle, _, err := g.current()
+		if err != nil {
+			p.refuse(err)
+			return
+		}
+		correct, err := g.answer(guess)
+		if err != nil {
+			p.refuse(err)
+			return
+		}
+		result := apiResult{Correct: correct, Status: p.status()}
+		if !correct && g.settings.RevealPolicy == revealImmediately {
+			result.Answer = riddle.Answer
+		}
+		p.send("result", result)
+		if g.hanged() {
+			p.endRound(endHanged)
+		}
+	case "hint":
+		hint, err := g.hint()
+		if err != nil {
+			p.refuse(err)
+			return
+		}
+		p.send("hint", apiHint{
+			Hint:   hint,
+			Used:   g.session.Progress.Results[g.asking].HintsUsed,
+			Total:  len(g.session.Progress.Riddles[g.asking].Hints),
+			Cost:   g.settings.HintCost,
+			Status: p.status(),
+		})
+	case "pass":
+		if err := g.pass(); err != nil {
+			p.refuse(err)
+			return
+		}
+		p.send("passed", p.status())
+	case "skip":
+		riddle, _, err := g.current()
+		if err != nil {
+			p.refuse(err)
+			return
+		}
+		if err := g.skip(); err != nil {
+			p.refuse(err)
+			return
+		}
+		result := apiResult{Status: p.status()}
+		if g.settings.RevealPolicy == revealImmediately {
+			result.Answer = riddle.Answer
+		}
+		p.send("skipped", result)
+	case "time":
+		p.send("time", p.status())
+	case "quit":
+		p.endRound(endQuit)
+	default:
+		p.sendError(fmt.Errorf("unknown action %q", msg.Action))
+	}
+}
 
-				// Run riddles with a timer
-				runRiddles(team, riddlesSubset, reader)
+// refuse tells the player a move wasn't allowed. A move that came in after
+// the clock ran out moves the game on as well.
+func (p *webPlayer) refuse(err error) {
+	p.sendError(err)
+	if err == errTimeUp {
+		p.next()
+	}
+}
 
-				for {
-					fmt.Print(green("Type 'close' to exit: "))
-					command, _ := reader.ReadString('\n')
-					command = strings.TrimSpace(strings.ToLower(command))
+// login checks the team in the same way the terminal does, creating it with
+// the given password on its first login.
+func (p *webPlayer) login(teamName, password string) {
+	if p.team != nil {
+		p.sendError(errors.New("already logged in"))
+		return
+	}
 
-					if command == "close" {
-						fmt.Println(blue("Exiting the game..."))
-						return
-					} else {
-						fmt.Println(red("Invalid command. Please type 'close'."))
-					}
-				}
-			} else if command == "close" {
-				fmt.Println(blue("Exiting..."))
-				break
-			} else {
-				fmt.Println(red("Invalid command"))
+	teamName = strings.TrimSpace(strings.ToLower(teamName))
+	approvedTeams, err := getApprovedTeamsFromFirebase()
+	if err != nil {
+		log.Printf("Error fetching approved teams: %v\n", err)
+		p.sendError(errors.New("the game is unavailable right now"))
+		return
+	}
+	if !validateTeam(approvedTeams, teamName) {
+		p.sendError(errors.New("your team is not on the approved list"))
+		return
+	}
+
+	// The password is checked before the team is kept from other players
+	// or its attempts are looked at
+	existingTeam, err := getTeamFromFirebase(teamName)
+	found := err == nil
+	if found && existingTeam.Password != "" && password != existingTeam.Password {
+		p.failedPasswords++
+		if p.failedPasswords == maxPasswordTries {
+			p.sendError(errors.New("too many incorrect passwords"))
+			p.ws.Close()
+			return
+		}
+		p.sendError(errors.New("incorrect password"))
+		return
+	}
+	if !found && password == "" {
+		p.sendError(errors.New("this is your first login; choose a password"))
+		return
+	}
+
+	release, ok := claimTeam(teamName)
+	if !ok {
+		p.sendError(errors.New("your team is already playing elsewhere"))
+		return
+	}
+	// The claim is kept only once the login succeeds
+	loggedIn := false
+	defer func() {
+		if !loggedIn {
+			release()
+		}
+	}()
+
+	settings, err := getGameSettingsFromFirebase()
+	if err != nil {
+		log.Printf("Error getting game settings: %v. Using defaults.\n", err)
+	}
+
+	var resumable *Session
+	if found {
+		resumable, err = beginAttempt(&existingTeam, settings)
+		if err != nil {
+			p.sendError(err)
+			return
+		}
+	} else {
+		existingTeam = Team{Name: teamName, Score: 0, Attempts: 1, Password: password}
+		saveTeamToFirebase(existingTeam)
+	}
+
+	loggedIn = true
+	p.team = &existingTeam
+	p.resumable = resumable
+	p.release = release
+	p.send("login", apiLogin{
+		Team:     p.team.Name,
+		Attempt:  p.team.Attempts,
+		Resuming: resumable != nil,
+		Scoring:  settings.Scoring,
+	})
+}
+
+// start begins the logged-in team's game, or resumes its unfinished one.
+func (p *webPlayer) start() {
+	if p.team == nil {
+		p.sendError(errors.New("log in first"))
+		return
+	}
+	if p.game != nil {
+		p.sendError(errors.New("a game is already in play"))
+		return
+	}
+	// Checked before the lobby too, so the team isn't turned away only
+	// once the event has started without it
+	if settings, _ := getGameSettingsFromFirebase(); settings.Race && p.resumable == nil {
+		p.sendError(errRaceAtTerminals)
+		return
+	}
+	left, stopWatching := p.watchLobby()
+	err := waitForEvent(p.resumable != nil, left, func(stage string, startsIn int) {
+		p.send("lobby", apiLobby{State: stage, StartsIn: startsIn})
+	})
+	stopWatching()
+	if err != nil {
+		p.sendError(err)
+		return
+	}
+
+	gameDuration, err := getGameDurationFromFirebase()
+	if err != nil {
+		log.Printf("Error getting game duration: %v. Using default of 5 minutes.\n", err)
+		gameDuration = 5 * time.Minute
+	}
+	settings, err := getGameSettingsFromFirebase()
+	if err != nil {
+		log.Printf("Error getting game settings: %v. Using defaults.\n", err)
+	}
+	if settings.Race && p.resumable == nil {
+		p.sendError(errRaceAtTerminals)
+		return
+	}
+
+	session := p.resumable
+	if session == nil {
+		session = &Session{Team: p.team.Name, Attempt: p.team.Attempts, StartedAt: time.Now()}
+	}
+	g := newGame(p.team, session, settings)
+	notice, err := g.start(gameDuration)
+	if err != nil {
+		log.Printf("Error starting game: %v\n", err)
+		p.sendError(errors.New("the game couldn't be started"))
+		return
+	}
+
+	p.game = g
+	p.stopUpdater = startScoreUpdater(p.team)
+	addActiveGame(g, activeGame{stopUpdater: p.stopUpdater, notify: func(text string) {
+		p.send("notice", text)
+	}})
+	g.beginRound()
+
+	started := apiStarted{Notice: notice, Status: p.status()}
+	if session.Progress.Themed {
+		started.Rounds = session.Progress.Rounds
+	}
+	p.send("started", started)
+}
+
+// next sends the riddle to answer, moving through the rounds as they run
+// out.
+func (p *webPlayer) next() {
+	g := p.game
+	for {
+		progress := g.session.Progress
+		finalPass := progress.FinalPass
+		i, reason := g.question()
+		if reason == "" {
+			riddle := progress.Riddles[i]
+			question := apiQuestion{
+				Number:     i + 1,
+				Question:   riddle.Question,
+				Difficulty: riddleDifficulty(riddle),
+				Points:     riddlePoints(riddle),
+				Hints:      len(riddle.Hints),
+				HintCost:   g.settings.HintCost,
+				Revisit:    progress.FinalPass != finalPass,
+				Status:     p.status(),
 			}
+			if progress.Themed {
+				question.Round = progress.Rounds[progress.Round].Name
+			}
+			p.send("question", question)
+			return
+		}
+		if !p.endRound(reason) {
+			return
+		}
+	}
+}
+
+// endRound ends the round in play for reason and starts the next one,
+// reporting whether there is one. Once the game is over it is saved and the
+// player sent the summary.
+func (p *webPlayer) endRound(reason string) bool {
+	g := p.game
+	themed := g.session.Progress.Themed
+	more := g.endRound(reason)
+	if themed {
+		p.send("roundOver", g.session.Rounds[len(g.session.Rounds)-1])
+	}
+	if more {
+		g.beginRound()
+		return true
+	}
+
+	g.finish()
+	removeActiveGame(g)
+	p.stopUpdater()
+
+	// Answers to missed riddles stay hidden when the event says so
+	summary := *g.session
+	summary.Results = slices.Clone(summary.Results)
+	for i, result := range summary.Results {
+		if !result.Correct && g.settings.RevealPolicy == revealNever {
+			summary.Results[i].Answer = ""
 		}
 	}
+	p.send("gameOver", summary)
+
+	// The team is logged out, free to log in again here or elsewhere
+	p.release()
+	p.team, p.resumable, p.game, p.release = nil, nil, nil, nil
+	return false
 }
 
 func main() {
 	initFirebase()
-	userInterface()
+	handleShutdown()
+	go pollAnnouncements(time.Second)
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		flags := flag.NewFlagSet("serve", flag.ExitOnError)
+		addr := flags.String("addr", ":2323", "address to serve the game on")
+		httpAddr := flags.String("http", ":8080", "address to serve the leaderboard and web API on, or empty for none")
+		flags.Parse(os.Args[2:])
+		serve(*addr, *httpAddr)
+		return
+	}
+	userInterface(localTerminal(), false)
 }
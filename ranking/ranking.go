@@ -0,0 +1,141 @@
+// Package ranking puts teams in leaderboard order. The game and the admin
+// tool both rank teams with it, so their leaderboards always agree.
+package ranking
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// How a team's standing is worked out from the scores of its attempts.
+const (
+	ScoreLatest  = "latest"
+	ScoreBest    = "best"
+	ScoreAverage = "average"
+)
+
+// Tiebreakers for teams on the same score, applied in the order the event
+// lists them.
+const (
+	TiebreakWrongGuesses = "wrongGuesses" // Fewer wrong guesses
+	TiebreakTime         = "time"         // Less time spent answering
+	TiebreakHints        = "hints"        // Fewer hints used
+	TiebreakFinish       = "finish"       // Finished earlier
+)
+
+// DefaultTiebreakers are used when the event doesn't list its own.
+var DefaultTiebreakers = []string{TiebreakWrongGuesses, TiebreakTime, TiebreakHints, TiebreakFinish}
+
+// Attempt is what the ranking needs of one of a team's attempts.
+type Attempt struct {
+	Number       int
+	Score        int
+	WrongGuesses int
+	Seconds      float64 // Time spent answering
+	Hints        int
+	Finished     bool
+	EndedAt      time.Time
+}
+
+// Standing is a team's place on the leaderboard and the record behind it.
+type Standing struct {
+	Team         string
+	Score        int
+	WrongGuesses int
+	Seconds      float64 // Time spent answering
+	Hints        int
+	FinishedAt   time.Time // Zero until the team has finished
+	Rank         int
+}
+
+// TeamStanding sums up the attempts that count towards the team's score:
+// the latest, the best, or all of them when they are averaged. score is
+// the team's score as the game keeps it.
+func TeamStanding(team string, score int, attempts []Attempt, scoring string) Standing {
+	counted := attempts
+	if len(attempts) > 0 && scoring != ScoreAverage {
+		pick := attempts[0]
+		for _, attempt := range attempts[1:] {
+			better := attempt.Number > pick.Number
+			if scoring == ScoreBest {
+				// The earlier attempt counts when two have the best score
+				better = attempt.Score > pick.Score || attempt.Score == pick.Score && attempt.Number < pick.Number
+			}
+			if better {
+				pick = attempt
+			}
+		}
+		counted = []Attempt{pick}
+	}
+
+	result := Standing{Team: team, Score: score}
+	finished := len(counted) > 0
+	for _, attempt := range counted {
+		result.WrongGuesses += attempt.WrongGuesses
+		result.Seconds += attempt.Seconds
+		result.Hints += attempt.Hints
+		if !attempt.Finished {
+			finished = false
+		} else if attempt.EndedAt.After(result.FinishedAt) {
+			result.FinishedAt = attempt.EndedAt
+		}
+	}
+	if !finished {
+		result.FinishedAt = time.Time{}
+	}
+	return result
+}
+
+// Compare orders a before b when it has the higher score, then by each
+// tiebreaker in turn.
+func Compare(a, b Standing, tiebreakers []string) int {
+	if a.Score != b.Score {
+		return cmp.Compare(b.Score, a.Score)
+	}
+	for _, tiebreaker := range tiebreakers {
+		var c int
+		switch tiebreaker {
+		case TiebreakWrongGuesses:
+			c = cmp.Compare(a.WrongGuesses, b.WrongGuesses)
+		case TiebreakTime:
+			c = cmp.Compare(a.Seconds, b.Seconds)
+		case TiebreakHints:
+			c = cmp.Compare(a.Hints, b.Hints)
+		case TiebreakFinish:
+			// Teams still playing come after every team that has finished
+			switch {
+			case a.FinishedAt.IsZero() && b.FinishedAt.IsZero():
+			case a.FinishedAt.IsZero():
+				c = 1
+			case b.FinishedAt.IsZero():
+				c = -1
+			default:
+				c = a.FinishedAt.Compare(b.FinishedAt)
+			}
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// Rank puts the standings in leaderboard order and fills in their ranks.
+// Teams that can't be told apart by score or any tiebreaker share a rank.
+func Rank(standings []Standing, tiebreakers []string) {
+	slices.SortStableFunc(standings, func(a, b Standing) int {
+		if c := Compare(a, b, tiebreakers); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Team, b.Team)
+	})
+
+	for i := range standings {
+		standings[i].Rank = i + 1
+		if i > 0 && Compare(standings[i-1], standings[i], tiebreakers) == 0 {
+			standings[i].Rank = standings[i-1].Rank
+		}
+	}
+}
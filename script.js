@@ -1,75 +1,88 @@
-const firebaseConfig = {}; // copy paste the firebase credientials here
+// The leaderboard is served by the game server (`hangman serve`), which
+// pushes a new ranking over server-sent events whenever it changes, along
+// with the organizers' announcements. Only each team's public standing ever
+// reaches the browser: its rank, name and score, and the wrong guesses,
+// time, hints and whether it finished that break ties.
 
-try {
-    firebase.initializeApp(firebaseConfig);
-    const db = firebase.firestore();
+function showTopTeam(place, team) {
+    document.getElementById(`${place}-team`).textContent = team ? team.name : '';
+    document.getElementById(`${place}-score`).textContent = team ? `Score: ${team.score}` : '';
+}
 
-    function updateScores() {
-        db.collection("teams").onSnapshot((snapshot) => {
-            let teams = [];
-            snapshot.forEach((doc) => {
-                teams.push({ id: doc.id, ...doc.data() });
-            });
+function renderLeaderboard(teams) {
+    const errorMessage = document.getElementById('errorMessage');
+    if (teams.length === 0) {
+        errorMessage.textContent = "No teams found yet.";
+    } else {
+        errorMessage.textContent = '';
+    }
+
+    const scoreBoard = document.getElementById('scoreBoard');
+
+    // Clear previous content
+    scoreBoard.innerHTML = '';
+
+    // Add the header
+    const headerRow = document.createElement('div');
+    headerRow.className = 'scoreboard-header';
+    headerRow.innerHTML = `
+        <span>Place</span>
+        <span>Team Name</span>
+        <span>Score</span>
+    `;
+    scoreBoard.appendChild(headerRow);
+
+    // Update the top 3 teams with 3D prize effects
+    showTopTeam('first', teams[0]);
+    showTopTeam('second', teams[1]);
+    showTopTeam('third', teams[2]);
+
+    // Display teams in the leaderboard, starting from the 4th place
+    teams.slice(3).forEach((team) => {
+        const teamElement = document.createElement('div');
+        teamElement.className = 'team';
 
-            if (teams.length === 0) {
-                document.getElementById('errorMessage').textContent = "No teams found in the database.";
-                return;
-            }
+        const place = document.createElement('span');
+        place.textContent = team.rank;
+        const name = document.createElement('span');
+        name.className = 'team-name';
+        name.textContent = team.name;
+        const score = document.createElement('span');
+        score.className = 'team-score';
+        score.textContent = team.score;
 
-            // Sort teams by score in descending order (highest score first)
-            teams.sort((a, b) => (b.score || 0) - (a.score || 0));
+        teamElement.append(place, name, score);
+        scoreBoard.appendChild(teamElement);
+    });
 
-            const scoreBoard = document.getElementById('scoreBoard');
-            
-            // Clear previous content
-            scoreBoard.innerHTML = '';
-            
-            // Add the header
-            const headerRow = document.createElement('div');
-            headerRow.className = 'scoreboard-header';
-            headerRow.innerHTML = `
-                <span>Place</span>
-                <span>Team Name</span>
-                <span>Score</span>
-            `;
-            scoreBoard.appendChild(headerRow);
+    console.log("Scores updated successfully");
+}
 
-            // Update the top 3 teams with 3D prize effects
-            if (teams[0]) {
-                document.getElementById('first-team').textContent = teams[0].name || 'Unknown';
-                document.getElementById('first-score').textContent = teams[0].score != null ? `Score: ${teams[0].score}` : 'N/A';
-            }
-            if (teams[1]) {
-                document.getElementById('second-team').textContent = teams[1].name || 'Unknown';
-                document.getElementById('second-score').textContent = teams[1].score != null ? `Score: ${teams[1].score}` : 'N/A';
-            }
-            if (teams[2]) {
-                document.getElementById('third-team').textContent = teams[2].name || 'Unknown';
-                document.getElementById('third-score').textContent = teams[2].score != null ? `Score: ${teams[2].score}` : 'N/A';
-            }
+// EventSource reconnects by itself if the server goes away for a while
+const feed = new EventSource('/api/leaderboard/stream');
+feed.addEventListener('leaderboard', (event) => {
+    renderLeaderboard(JSON.parse(event.data));
+});
 
-            // Display teams in the leaderboard, starting from the 4th place
-            teams.slice(3).forEach((team, index) => {
-                const teamElement = document.createElement('div');
-                teamElement.className = 'team';
-                teamElement.innerHTML = `
-                    <span>${index + 4}</span>
-                    <span class="team-name">${team.name || 'Unknown'}</span>
-                    <span class="team-score">${team.score != null ? team.score : 'N/A'}</span>
-                `;
-                scoreBoard.appendChild(teamElement);
-            });
+// Announcements stay up for a couple of minutes, or until the next one
+const announcementTime = 2 * 60 * 1000;
+let announcementTimer;
 
-            console.log("Scores updated successfully");
-        }, (error) => {
-            console.error("Error fetching scores:", error);
-            document.getElementById('errorMessage').textContent = "Error fetching scores. Please check the console for details.";
-        });
+function showAnnouncement(announcement) {
+    const shownFor = announcementTime - (Date.now() - Date.parse(announcement.sentAt));
+    if (shownFor <= 0) {
+        return;
     }
+    const banner = document.getElementById('announcement');
+    banner.textContent = announcement.text;
+    banner.hidden = false;
+    clearTimeout(announcementTimer);
+    announcementTimer = setTimeout(() => { banner.hidden = true; }, shownFor);
+}
 
-    updateScores();
-    console.log("Script loaded and executed");
-} catch (error) {
-    console.error("Error initializing Firebase:", error);
-    document.getElementById('errorMessage').textContent = "Error initializing Firebase. Please check the console for details.";
-}
\ No newline at end of file
+feed.addEventListener('announcement', (event) => {
+    showAnnouncement(JSON.parse(event.data));
+});
+feed.onerror = () => {
+    document.getElementById('errorMessage').textContent = "Lost connection to the game server. Retrying...";
+};